@@ -111,6 +111,146 @@ func (x *GenerateSequenceResponse) GetSequence() []uint64 {
 	return nil
 }
 
+type StreamSequenceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint32                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Value         uint64                 `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamSequenceResponse) Reset() {
+	*x = StreamSequenceResponse{}
+	mi := &file_api_v1_api_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamSequenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamSequenceResponse) ProtoMessage() {}
+
+func (x *StreamSequenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_api_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamSequenceResponse.ProtoReflect.Descriptor instead.
+func (*StreamSequenceResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StreamSequenceResponse) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *StreamSequenceResponse) GetValue() uint64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type GetNumberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint32                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"` // indexes start at zero
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNumberRequest) Reset() {
+	*x = GetNumberRequest{}
+	mi := &file_api_v1_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNumberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNumberRequest) ProtoMessage() {}
+
+func (x *GetNumberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNumberRequest.ProtoReflect.Descriptor instead.
+func (*GetNumberRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetNumberRequest) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type GetNumberResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Number        uint64                 `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNumberResponse) Reset() {
+	*x = GetNumberResponse{}
+	mi := &file_api_v1_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNumberResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNumberResponse) ProtoMessage() {}
+
+func (x *GetNumberResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNumberResponse.ProtoReflect.Descriptor instead.
+func (*GetNumberResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetNumberResponse) GetNumber() uint64 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
 var File_api_v1_api_proto protoreflect.FileDescriptor
 
 const file_api_v1_api_proto_rawDesc = "" +
@@ -119,9 +259,18 @@ const file_api_v1_api_proto_rawDesc = "" +
 	"\x17GenerateSequenceRequest\x12!\n" +
 	"\x06length\x18\x01 \x01(\rB\t\xbaH\x06*\x04\x10_ \x00R\x06length\"6\n" +
 	"\x18GenerateSequenceResponse\x12\x1a\n" +
-	"\bsequence\x18\x01 \x03(\x04R\bsequence2|\n" +
-	"\tFibonacci\x12o\n" +
-	"\x10GenerateSequence\x12\x1f.api.v1.GenerateSequenceRequest\x1a .api.v1.GenerateSequenceResponse\"\x18\x82\xd3\xe4\x93\x02\x12\x12\x10/api/v1/generateB!Z\x1fgithub.com/domust/fibonacci/apib\x06proto3"
+	"\bsequence\x18\x01 \x03(\x04R\bsequence\"D\n" +
+	"\x16StreamSequenceResponse\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\rR\x05index\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value\"1\n" +
+	"\x10GetNumberRequest\x12\x1d\n" +
+	"\x05index\x18\x01 \x01(\rB\a\xbaH\x04*\x02\x10^R\x05index\"+\n" +
+	"\x11GetNumberResponse\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\x04R\x06number2\xf4\x02\n" +
+	"\tFibonacci\x12\xae\x01\n" +
+	"\x10GenerateSequence\x12\x1f.api.v1.GenerateSequenceRequest\x1a .api.v1.GenerateSequenceResponse\"W\x82\xd3\xe4\x93\x02QZ\x1c\x12\x1a/api/v1/sequences/{length}Z\x1f:\x01*\"\x1a/api/v1/sequences:generate\x12\x10/api/v1/generate\x12a\n" +
+	"\tGetNumber\x12\x18.api.v1.GetNumberRequest\x1a\x19.api.v1.GetNumberResponse\"\x1f\x82\xd3\xe4\x93\x02\x19\x12\x17/api/v1/numbers/{index}\x12S\n" +
+	"\x0eStreamSequence\x12\x1f.api.v1.GenerateSequenceRequest\x1a\x1e.api.v1.StreamSequenceResponse0\x01B!Z\x1fgithub.com/domust/fibonacci/apib\x06proto3"
 
 var (
 	file_api_v1_api_proto_rawDescOnce sync.Once
@@ -135,16 +284,23 @@ func file_api_v1_api_proto_rawDescGZIP() []byte {
 	return file_api_v1_api_proto_rawDescData
 }
 
-var file_api_v1_api_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_api_v1_api_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_api_v1_api_proto_goTypes = []any{
 	(*GenerateSequenceRequest)(nil),  // 0: api.v1.GenerateSequenceRequest
 	(*GenerateSequenceResponse)(nil), // 1: api.v1.GenerateSequenceResponse
+	(*StreamSequenceResponse)(nil),   // 2: api.v1.StreamSequenceResponse
+	(*GetNumberRequest)(nil),         // 3: api.v1.GetNumberRequest
+	(*GetNumberResponse)(nil),        // 4: api.v1.GetNumberResponse
 }
 var file_api_v1_api_proto_depIdxs = []int32{
 	0, // 0: api.v1.Fibonacci.GenerateSequence:input_type -> api.v1.GenerateSequenceRequest
-	1, // 1: api.v1.Fibonacci.GenerateSequence:output_type -> api.v1.GenerateSequenceResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
+	3, // 1: api.v1.Fibonacci.GetNumber:input_type -> api.v1.GetNumberRequest
+	0, // 2: api.v1.Fibonacci.StreamSequence:input_type -> api.v1.GenerateSequenceRequest
+	1, // 3: api.v1.Fibonacci.GenerateSequence:output_type -> api.v1.GenerateSequenceResponse
+	4, // 4: api.v1.Fibonacci.GetNumber:output_type -> api.v1.GetNumberResponse
+	2, // 5: api.v1.Fibonacci.StreamSequence:output_type -> api.v1.StreamSequenceResponse
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -161,7 +317,7 @@ func file_api_v1_api_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_v1_api_proto_rawDesc), len(file_api_v1_api_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,6 +20,8 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	Fibonacci_GenerateSequence_FullMethodName = "/api.v1.Fibonacci/GenerateSequence"
+	Fibonacci_GetNumber_FullMethodName        = "/api.v1.Fibonacci/GetNumber"
+	Fibonacci_StreamSequence_FullMethodName   = "/api.v1.Fibonacci/StreamSequence"
 )
 
 // FibonacciClient is the client API for Fibonacci service.
@@ -27,6 +29,9 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type FibonacciClient interface {
 	GenerateSequence(ctx context.Context, in *GenerateSequenceRequest, opts ...grpc.CallOption) (*GenerateSequenceResponse, error)
+	GetNumber(ctx context.Context, in *GetNumberRequest, opts ...grpc.CallOption) (*GetNumberResponse, error)
+	// StreamSequence sends terms as they are generated, browsers consume it over WebSockets served by the gateway.
+	StreamSequence(ctx context.Context, in *GenerateSequenceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamSequenceResponse], error)
 }
 
 type fibonacciClient struct {
@@ -47,11 +52,43 @@ func (c *fibonacciClient) GenerateSequence(ctx context.Context, in *GenerateSequ
 	return out, nil
 }
 
+func (c *fibonacciClient) GetNumber(ctx context.Context, in *GetNumberRequest, opts ...grpc.CallOption) (*GetNumberResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetNumberResponse)
+	err := c.cc.Invoke(ctx, Fibonacci_GetNumber_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fibonacciClient) StreamSequence(ctx context.Context, in *GenerateSequenceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamSequenceResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Fibonacci_ServiceDesc.Streams[0], Fibonacci_StreamSequence_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GenerateSequenceRequest, StreamSequenceResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Fibonacci_StreamSequenceClient = grpc.ServerStreamingClient[StreamSequenceResponse]
+
 // FibonacciServer is the server API for Fibonacci service.
 // All implementations must embed UnimplementedFibonacciServer
 // for forward compatibility.
 type FibonacciServer interface {
 	GenerateSequence(context.Context, *GenerateSequenceRequest) (*GenerateSequenceResponse, error)
+	GetNumber(context.Context, *GetNumberRequest) (*GetNumberResponse, error)
+	// StreamSequence sends terms as they are generated, browsers consume it over WebSockets served by the gateway.
+	StreamSequence(*GenerateSequenceRequest, grpc.ServerStreamingServer[StreamSequenceResponse]) error
 	mustEmbedUnimplementedFibonacciServer()
 }
 
@@ -65,6 +102,12 @@ type UnimplementedFibonacciServer struct{}
 func (UnimplementedFibonacciServer) GenerateSequence(context.Context, *GenerateSequenceRequest) (*GenerateSequenceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GenerateSequence not implemented")
 }
+func (UnimplementedFibonacciServer) GetNumber(context.Context, *GetNumberRequest) (*GetNumberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNumber not implemented")
+}
+func (UnimplementedFibonacciServer) StreamSequence(*GenerateSequenceRequest, grpc.ServerStreamingServer[StreamSequenceResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamSequence not implemented")
+}
 func (UnimplementedFibonacciServer) mustEmbedUnimplementedFibonacciServer() {}
 func (UnimplementedFibonacciServer) testEmbeddedByValue()                   {}
 
@@ -104,6 +147,35 @@ func _Fibonacci_GenerateSequence_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Fibonacci_GetNumber_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNumberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FibonacciServer).GetNumber(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Fibonacci_GetNumber_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FibonacciServer).GetNumber(ctx, req.(*GetNumberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Fibonacci_StreamSequence_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GenerateSequenceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FibonacciServer).StreamSequence(m, &grpc.GenericServerStream[GenerateSequenceRequest, StreamSequenceResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Fibonacci_StreamSequenceServer = grpc.ServerStreamingServer[StreamSequenceResponse]
+
 // Fibonacci_ServiceDesc is the grpc.ServiceDesc for Fibonacci service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,7 +187,17 @@ var Fibonacci_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GenerateSequence",
 			Handler:    _Fibonacci_GenerateSequence_Handler,
 		},
+		{
+			MethodName: "GetNumber",
+			Handler:    _Fibonacci_GetNumber_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamSequence",
+			Handler:       _Fibonacci_StreamSequence_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/v1/api.proto",
 }
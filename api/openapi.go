@@ -0,0 +1,8 @@
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI v2 document describing the REST API, generated from the protobuf definitions.
+//
+//go:embed api.swagger.json
+var OpenAPI []byte
@@ -0,0 +1,403 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/v2/api.proto
+
+package apiv2
+
+import (
+	_ "buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Number is a term of the sequence.
+type Number struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// index of the term, starting at zero.
+	Index uint32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*Number_Uint64
+	//	*Number_Big
+	Value         isNumber_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Number) Reset() {
+	*x = Number{}
+	mi := &file_api_v2_api_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Number) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Number) ProtoMessage() {}
+
+func (x *Number) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_api_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Number.ProtoReflect.Descriptor instead.
+func (*Number) Descriptor() ([]byte, []int) {
+	return file_api_v2_api_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Number) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Number) GetValue() isNumber_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Number) GetUint64() uint64 {
+	if x != nil {
+		if x, ok := x.Value.(*Number_Uint64); ok {
+			return x.Uint64
+		}
+	}
+	return 0
+}
+
+func (x *Number) GetBig() string {
+	if x != nil {
+		if x, ok := x.Value.(*Number_Big); ok {
+			return x.Big
+		}
+	}
+	return ""
+}
+
+type isNumber_Value interface {
+	isNumber_Value()
+}
+
+type Number_Uint64 struct {
+	// uint64 holds terms that fit into 64 bits, i.e. up to index 93.
+	Uint64 uint64 `protobuf:"varint,2,opt,name=uint64,proto3,oneof"`
+}
+
+type Number_Big struct {
+	// big holds larger terms in decimal notation.
+	Big string `protobuf:"bytes,3,opt,name=big,proto3,oneof"`
+}
+
+func (*Number_Uint64) isNumber_Value() {}
+
+func (*Number_Big) isNumber_Value() {}
+
+type ListNumbersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// page_size is the number of terms per page, 100 when unset.
+	PageSize uint32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// page_token is the next_page_token of the previous page, the first page is returned when unset.
+	PageToken     string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNumbersRequest) Reset() {
+	*x = ListNumbersRequest{}
+	mi := &file_api_v2_api_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNumbersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNumbersRequest) ProtoMessage() {}
+
+func (x *ListNumbersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_api_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNumbersRequest.ProtoReflect.Descriptor instead.
+func (*ListNumbersRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListNumbersRequest) GetPageSize() uint32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListNumbersRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListNumbersResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Numbers []*Number              `protobuf:"bytes,1,rep,name=numbers,proto3" json:"numbers,omitempty"`
+	// next_page_token is empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNumbersResponse) Reset() {
+	*x = ListNumbersResponse{}
+	mi := &file_api_v2_api_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNumbersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNumbersResponse) ProtoMessage() {}
+
+func (x *ListNumbersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_api_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNumbersResponse.ProtoReflect.Descriptor instead.
+func (*ListNumbersResponse) Descriptor() ([]byte, []int) {
+	return file_api_v2_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListNumbersResponse) GetNumbers() []*Number {
+	if x != nil {
+		return x.Numbers
+	}
+	return nil
+}
+
+func (x *ListNumbersResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type GetNumberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint32                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNumberRequest) Reset() {
+	*x = GetNumberRequest{}
+	mi := &file_api_v2_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNumberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNumberRequest) ProtoMessage() {}
+
+func (x *GetNumberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNumberRequest.ProtoReflect.Descriptor instead.
+func (*GetNumberRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetNumberRequest) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type StreamNumbersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Length        uint32                 `protobuf:"varint,1,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamNumbersRequest) Reset() {
+	*x = StreamNumbersRequest{}
+	mi := &file_api_v2_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamNumbersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamNumbersRequest) ProtoMessage() {}
+
+func (x *StreamNumbersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v2_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamNumbersRequest.ProtoReflect.Descriptor instead.
+func (*StreamNumbersRequest) Descriptor() ([]byte, []int) {
+	return file_api_v2_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamNumbersRequest) GetLength() uint32 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+var File_api_v2_api_proto protoreflect.FileDescriptor
+
+const file_api_v2_api_proto_rawDesc = "" +
+	"\n" +
+	"\x10api/v2/api.proto\x12\x06api.v2\x1a\x1bbuf/validate/validate.proto\x1a\x1cgoogle/api/annotations.proto\"U\n" +
+	"\x06Number\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\rR\x05index\x12\x18\n" +
+	"\x06uint64\x18\x02 \x01(\x04H\x00R\x06uint64\x12\x12\n" +
+	"\x03big\x18\x03 \x01(\tH\x00R\x03bigB\a\n" +
+	"\x05value\"Z\n" +
+	"\x12ListNumbersRequest\x12%\n" +
+	"\tpage_size\x18\x01 \x01(\rB\b\xbaH\x05*\x03\x18\xe8\aR\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\"g\n" +
+	"\x13ListNumbersResponse\x12(\n" +
+	"\anumbers\x18\x01 \x03(\v2\x0e.api.v2.NumberR\anumbers\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"2\n" +
+	"\x10GetNumberRequest\x12\x1e\n" +
+	"\x05index\x18\x01 \x01(\rB\b\xbaH\x05*\x03\x10\x90NR\x05index\":\n" +
+	"\x14StreamNumbersRequest\x12\"\n" +
+	"\x06length\x18\x01 \x01(\rB\n" +
+	"\xbaH\a*\x05\x18\x90N \x00R\x06length2\x85\x02\n" +
+	"\tFibonacci\x12_\n" +
+	"\vListNumbers\x12\x1a.api.v2.ListNumbersRequest\x1a\x1b.api.v2.ListNumbersResponse\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/api/v2/numbers\x12V\n" +
+	"\tGetNumber\x12\x18.api.v2.GetNumberRequest\x1a\x0e.api.v2.Number\"\x1f\x82\xd3\xe4\x93\x02\x19\x12\x17/api/v2/numbers/{index}\x12?\n" +
+	"\rStreamNumbers\x12\x1c.api.v2.StreamNumbersRequest\x1a\x0e.api.v2.Number0\x01B*Z(github.com/domust/fibonacci/api/v2;apiv2b\x06proto3"
+
+var (
+	file_api_v2_api_proto_rawDescOnce sync.Once
+	file_api_v2_api_proto_rawDescData []byte
+)
+
+func file_api_v2_api_proto_rawDescGZIP() []byte {
+	file_api_v2_api_proto_rawDescOnce.Do(func() {
+		file_api_v2_api_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_v2_api_proto_rawDesc), len(file_api_v2_api_proto_rawDesc)))
+	})
+	return file_api_v2_api_proto_rawDescData
+}
+
+var file_api_v2_api_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_api_v2_api_proto_goTypes = []any{
+	(*Number)(nil),               // 0: api.v2.Number
+	(*ListNumbersRequest)(nil),   // 1: api.v2.ListNumbersRequest
+	(*ListNumbersResponse)(nil),  // 2: api.v2.ListNumbersResponse
+	(*GetNumberRequest)(nil),     // 3: api.v2.GetNumberRequest
+	(*StreamNumbersRequest)(nil), // 4: api.v2.StreamNumbersRequest
+}
+var file_api_v2_api_proto_depIdxs = []int32{
+	0, // 0: api.v2.ListNumbersResponse.numbers:type_name -> api.v2.Number
+	1, // 1: api.v2.Fibonacci.ListNumbers:input_type -> api.v2.ListNumbersRequest
+	3, // 2: api.v2.Fibonacci.GetNumber:input_type -> api.v2.GetNumberRequest
+	4, // 3: api.v2.Fibonacci.StreamNumbers:input_type -> api.v2.StreamNumbersRequest
+	2, // 4: api.v2.Fibonacci.ListNumbers:output_type -> api.v2.ListNumbersResponse
+	0, // 5: api.v2.Fibonacci.GetNumber:output_type -> api.v2.Number
+	0, // 6: api.v2.Fibonacci.StreamNumbers:output_type -> api.v2.Number
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_api_v2_api_proto_init() }
+func file_api_v2_api_proto_init() {
+	if File_api_v2_api_proto != nil {
+		return
+	}
+	file_api_v2_api_proto_msgTypes[0].OneofWrappers = []any{
+		(*Number_Uint64)(nil),
+		(*Number_Big)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_v2_api_proto_rawDesc), len(file_api_v2_api_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_v2_api_proto_goTypes,
+		DependencyIndexes: file_api_v2_api_proto_depIdxs,
+		MessageInfos:      file_api_v2_api_proto_msgTypes,
+	}.Build()
+	File_api_v2_api_proto = out.File
+	file_api_v2_api_proto_goTypes = nil
+	file_api_v2_api_proto_depIdxs = nil
+}
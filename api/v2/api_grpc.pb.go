@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/v2/api.proto
+
+package apiv2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Fibonacci_ListNumbers_FullMethodName   = "/api.v2.Fibonacci/ListNumbers"
+	Fibonacci_GetNumber_FullMethodName     = "/api.v2.Fibonacci/GetNumber"
+	Fibonacci_StreamNumbers_FullMethodName = "/api.v2.Fibonacci/StreamNumbers"
+)
+
+// FibonacciClient is the client API for Fibonacci service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Fibonacci represents terms as numbers carrying their index, which are not limited to 64 bits.
+type FibonacciClient interface {
+	// ListNumbers pages through the sequence, starting with the first term.
+	ListNumbers(ctx context.Context, in *ListNumbersRequest, opts ...grpc.CallOption) (*ListNumbersResponse, error)
+	// GetNumber returns the term at the index, starting at zero.
+	GetNumber(ctx context.Context, in *GetNumberRequest, opts ...grpc.CallOption) (*Number, error)
+	// StreamNumbers sends terms as they are generated.
+	StreamNumbers(ctx context.Context, in *StreamNumbersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Number], error)
+}
+
+type fibonacciClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFibonacciClient(cc grpc.ClientConnInterface) FibonacciClient {
+	return &fibonacciClient{cc}
+}
+
+func (c *fibonacciClient) ListNumbers(ctx context.Context, in *ListNumbersRequest, opts ...grpc.CallOption) (*ListNumbersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListNumbersResponse)
+	err := c.cc.Invoke(ctx, Fibonacci_ListNumbers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fibonacciClient) GetNumber(ctx context.Context, in *GetNumberRequest, opts ...grpc.CallOption) (*Number, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Number)
+	err := c.cc.Invoke(ctx, Fibonacci_GetNumber_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fibonacciClient) StreamNumbers(ctx context.Context, in *StreamNumbersRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Number], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Fibonacci_ServiceDesc.Streams[0], Fibonacci_StreamNumbers_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamNumbersRequest, Number]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Fibonacci_StreamNumbersClient = grpc.ServerStreamingClient[Number]
+
+// FibonacciServer is the server API for Fibonacci service.
+// All implementations must embed UnimplementedFibonacciServer
+// for forward compatibility.
+//
+// Fibonacci represents terms as numbers carrying their index, which are not limited to 64 bits.
+type FibonacciServer interface {
+	// ListNumbers pages through the sequence, starting with the first term.
+	ListNumbers(context.Context, *ListNumbersRequest) (*ListNumbersResponse, error)
+	// GetNumber returns the term at the index, starting at zero.
+	GetNumber(context.Context, *GetNumberRequest) (*Number, error)
+	// StreamNumbers sends terms as they are generated.
+	StreamNumbers(*StreamNumbersRequest, grpc.ServerStreamingServer[Number]) error
+	mustEmbedUnimplementedFibonacciServer()
+}
+
+// UnimplementedFibonacciServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedFibonacciServer struct{}
+
+func (UnimplementedFibonacciServer) ListNumbers(context.Context, *ListNumbersRequest) (*ListNumbersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNumbers not implemented")
+}
+func (UnimplementedFibonacciServer) GetNumber(context.Context, *GetNumberRequest) (*Number, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNumber not implemented")
+}
+func (UnimplementedFibonacciServer) StreamNumbers(*StreamNumbersRequest, grpc.ServerStreamingServer[Number]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamNumbers not implemented")
+}
+func (UnimplementedFibonacciServer) mustEmbedUnimplementedFibonacciServer() {}
+func (UnimplementedFibonacciServer) testEmbeddedByValue()                   {}
+
+// UnsafeFibonacciServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FibonacciServer will
+// result in compilation errors.
+type UnsafeFibonacciServer interface {
+	mustEmbedUnimplementedFibonacciServer()
+}
+
+func RegisterFibonacciServer(s grpc.ServiceRegistrar, srv FibonacciServer) {
+	// If the following call pancis, it indicates UnimplementedFibonacciServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Fibonacci_ServiceDesc, srv)
+}
+
+func _Fibonacci_ListNumbers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNumbersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FibonacciServer).ListNumbers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Fibonacci_ListNumbers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FibonacciServer).ListNumbers(ctx, req.(*ListNumbersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Fibonacci_GetNumber_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNumberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FibonacciServer).GetNumber(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Fibonacci_GetNumber_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FibonacciServer).GetNumber(ctx, req.(*GetNumberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Fibonacci_StreamNumbers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamNumbersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FibonacciServer).StreamNumbers(m, &grpc.GenericServerStream[StreamNumbersRequest, Number]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Fibonacci_StreamNumbersServer = grpc.ServerStreamingServer[Number]
+
+// Fibonacci_ServiceDesc is the grpc.ServiceDesc for Fibonacci service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Fibonacci_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "api.v2.Fibonacci",
+	HandlerType: (*FibonacciServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListNumbers",
+			Handler:    _Fibonacci_ListNumbers_Handler,
+		},
+		{
+			MethodName: "GetNumber",
+			Handler:    _Fibonacci_GetNumber_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamNumbers",
+			Handler:       _Fibonacci_StreamNumbers_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/v2/api.proto",
+}
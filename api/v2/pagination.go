@@ -0,0 +1,38 @@
+package apiv2
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// DefaultPageSize is the number of terms per page of requests that leave the page size unset.
+const DefaultPageSize = 100
+
+// MaxLength is the length of the sequence served by v2, which matches its validation rules.
+const MaxLength = 10000
+
+// PageToken returns the opaque token of the page starting with the term at the index.
+func PageToken(index uint32) string {
+	return base64.RawURLEncoding.EncodeToString(binary.AppendUvarint(nil, uint64(index)))
+}
+
+// ParsePageToken returns the index of the first term of the page, which is zero for the empty token of the first page.
+func ParsePageToken(token string) (uint32, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, errors.New("malformed page token")
+	}
+
+	index, n := binary.Uvarint(data)
+	if n != len(data) || index > math.MaxUint32 {
+		return 0, errors.New("malformed page token")
+	}
+
+	return uint32(index), nil
+}
@@ -0,0 +1,294 @@
+// Package accesslog emits a structured log record per grpc call and HTTP request.
+package accesslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/domust/fibonacci/internal/budget"
+)
+
+// Redacted replaces values of redacted fields.
+const Redacted = "[REDACTED]"
+
+// Logger writes access log records.
+type Logger struct {
+	logger *slog.Logger
+	rate   float64
+	redact map[string]bool
+	random func() float64
+}
+
+// New returns access logger writing records of successful calls with the given probability, while failed
+// calls are always logged. Values of the listed fields, such as "peer", are replaced with [Redacted].
+func New(logger *slog.Logger, rate float64, redact ...string) *Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	l := &Logger{
+		logger: logger,
+		rate:   rate,
+		redact: make(map[string]bool, len(redact)),
+		random: rand.Float64,
+	}
+	for _, field := range redact {
+		l.redact[field] = true
+	}
+
+	return l
+}
+
+// record is a single access log entry.
+type record struct {
+	protocol     string
+	method       string
+	peer         string
+	code         string
+	level        slog.Level
+	duration     time.Duration
+	requestSize  int
+	responseSize int
+	length       *uint64
+}
+
+func (l *Logger) log(ctx context.Context, r record) {
+	if r.level == slog.LevelInfo && l.random() >= l.rate {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("protocol", r.protocol),
+		slog.String("method", r.method),
+		slog.String("peer", r.peer),
+		slog.String("code", r.code),
+		slog.Duration("duration", r.duration),
+		slog.Int("request_size", r.requestSize),
+		slog.Int("response_size", r.responseSize),
+	}
+	if r.length != nil {
+		attrs = append(attrs, slog.Uint64("length", *r.length))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+
+	for i, attr := range attrs {
+		if l.redact[attr.Key] {
+			attrs[i] = slog.String(attr.Key, Redacted)
+		}
+	}
+
+	l.logger.LogAttrs(ctx, r.level, "access", attrs...)
+}
+
+func size(msg any) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return 0
+}
+
+func level(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unimplemented:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
+
+func remote(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// UnaryInterceptor logs every unary call once it completes.
+func (l *Logger) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		r := record{
+			protocol:     "grpc",
+			method:       info.FullMethod,
+			peer:         remote(ctx),
+			code:         code.String(),
+			level:        level(code),
+			duration:     time.Since(start),
+			requestSize:  size(req),
+			responseSize: size(resp),
+		}
+		if n, ok := budget.Length(req); ok {
+			length := uint64(n)
+			r.length = &length
+		}
+		l.log(ctx, r)
+
+		return resp, err
+	}
+}
+
+// StreamInterceptor logs every stream once it completes, with sizes summed over all messages.
+func (l *Logger) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		counted := &countingStream{ServerStream: ss}
+		err := handler(srv, counted)
+
+		code := status.Code(err)
+		l.log(ss.Context(), record{
+			protocol:     "grpc",
+			method:       info.FullMethod,
+			peer:         remote(ss.Context()),
+			code:         code.String(),
+			level:        level(code),
+			duration:     time.Since(start),
+			requestSize:  counted.received,
+			responseSize: counted.sent,
+			length:       counted.length,
+		})
+
+		return err
+	}
+}
+
+type countingStream struct {
+	grpc.ServerStream
+
+	received int
+	sent     int
+	length   *uint64
+}
+
+func (s *countingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	s.received += size(m)
+	if n, ok := budget.Length(m); ok && s.length == nil {
+		length := uint64(n)
+		s.length = &length
+	}
+
+	return nil
+}
+
+func (s *countingStream) SendMsg(m any) error {
+	s.sent += size(m)
+	return s.ServerStream.SendMsg(m)
+}
+
+// Middleware logs every HTTP request once it completes.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		body := &countingBody{ReadCloser: r.Body}
+		r.Body = body
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rw, r)
+
+		level := slog.LevelInfo
+		switch {
+		case rw.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case rw.status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		record := record{
+			protocol:     "http",
+			method:       r.Method + " " + r.URL.Path,
+			peer:         r.RemoteAddr,
+			code:         strconv.Itoa(rw.status),
+			level:        level,
+			duration:     time.Since(start),
+			requestSize:  body.read,
+			responseSize: rw.written,
+		}
+		// the length is left to the record of the proxied call, since routes carry it in the query, the path or the body
+		l.log(r.Context(), record)
+	})
+}
+
+type countingBody struct {
+	io.ReadCloser
+
+	read int
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += n
+	return n, err
+}
+
+// responseWriter records the status code and the size of the response.
+type responseWriter struct {
+	http.ResponseWriter
+
+	status      int
+	written     int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.written += n
+	return n, err
+}
+
+// Flush supports streaming responses.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack supports protocol upgrades, such as WebSockets.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.status = http.StatusSwitchingProtocols
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijacking is not supported")
+}
+
+// Unwrap allows [http.ResponseController] to reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
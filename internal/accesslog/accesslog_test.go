@@ -0,0 +1,245 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/domust/fibonacci/api"
+)
+
+const method = "/api.v1.Fibonacci/GenerateSequence"
+
+func records(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	decoder := json.NewDecoder(logs)
+	for decoder.More() {
+		var record map[string]any
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	tests := map[string]struct {
+		err   error
+		code  string
+		level string
+	}{
+		"ok": {
+			code:  "OK",
+			level: "INFO",
+		},
+		"client error": {
+			err:   status.Error(codes.InvalidArgument, "invalid"),
+			code:  "InvalidArgument",
+			level: "WARN",
+		},
+		"server error": {
+			err:   status.Error(codes.Internal, "internal"),
+			code:  "Internal",
+			level: "ERROR",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var logs bytes.Buffer
+			l := New(slog.New(slog.NewJSONHandler(&logs, nil)), 1)
+
+			ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), method)
+			defer span.End()
+			ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}})
+
+			req := &api.GenerateSequenceRequest{Length: 10}
+			resp := &api.GenerateSequenceResponse{Sequence: []uint64{0, 1, 1, 2, 3, 5, 8, 13, 21, 34}}
+			_, err := l.UnaryInterceptor()(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+				if test.err != nil {
+					return nil, test.err
+				}
+				return resp, nil
+			})
+			require.Equal(t, test.err, err)
+
+			logged := records(t, &logs)
+			require.Len(t, logged, 1)
+			record := logged[0]
+			require.Equal(t, test.level, record["level"])
+			require.Equal(t, "grpc", record["protocol"])
+			require.Equal(t, method, record["method"])
+			require.Equal(t, "10.0.0.1:1234", record["peer"])
+			require.Equal(t, test.code, record["code"])
+			require.Equal(t, float64(10), record["length"])
+			require.Equal(t, float64(proto.Size(req)), record["request_size"])
+			require.Equal(t, span.SpanContext().TraceID().String(), record["trace_id"])
+			require.Equal(t, span.SpanContext().SpanID().String(), record["span_id"])
+			require.Contains(t, record, "duration")
+			if test.err == nil {
+				require.Equal(t, float64(proto.Size(resp)), record["response_size"])
+			} else {
+				require.Equal(t, float64(0), record["response_size"])
+			}
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	var logs bytes.Buffer
+	l := New(slog.New(slog.NewJSONHandler(&logs, nil)), 1)
+
+	info := &grpc.UnaryServerInfo{FullMethod: api.Fibonacci_GetNumber_FullMethodName}
+	_, err := l.UnaryInterceptor()(context.Background(), &api.GetNumberRequest{Index: 9}, info, func(context.Context, any) (any, error) {
+		return &api.GetNumberResponse{}, nil
+	})
+	require.NoError(t, err)
+
+	logged := records(t, &logs)
+	require.Len(t, logged, 1)
+	require.Equal(t, float64(10), logged[0]["length"], "single terms take generating the sequence up to them")
+}
+
+func TestRedaction(t *testing.T) {
+	var logs bytes.Buffer
+	l := New(slog.New(slog.NewJSONHandler(&logs, nil)), 1, "peer", "length")
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}})
+	_, err := l.UnaryInterceptor()(ctx, &api.GenerateSequenceRequest{Length: 10}, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+		return &api.GenerateSequenceResponse{}, nil
+	})
+	require.NoError(t, err)
+
+	logged := records(t, &logs)
+	require.Len(t, logged, 1)
+	require.Equal(t, Redacted, logged[0]["peer"])
+	require.Equal(t, Redacted, logged[0]["length"])
+	require.Equal(t, method, logged[0]["method"])
+}
+
+func TestSampling(t *testing.T) {
+	var logs bytes.Buffer
+	l := New(slog.New(slog.NewJSONHandler(&logs, nil)), 0.5)
+	l.random = func() float64 { return 0.75 }
+
+	call := func(err error) {
+		_, _ = l.UnaryInterceptor()(context.Background(), &api.GenerateSequenceRequest{}, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+			return nil, err
+		})
+	}
+
+	// successful calls outside of the sample are dropped
+	call(nil)
+	require.Empty(t, records(t, &logs))
+
+	// failed calls are logged regardless of sampling
+	call(status.Error(codes.ResourceExhausted, "slow down"))
+	logged := records(t, &logs)
+	require.Len(t, logged, 1)
+	require.Equal(t, "ResourceExhausted", logged[0]["code"])
+
+	l.random = func() float64 { return 0.25 }
+	call(nil)
+	require.Len(t, records(t, &logs), 1)
+}
+
+type stream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s stream) Context() context.Context {
+	return s.ctx
+}
+
+func (s stream) RecvMsg(m any) error {
+	m.(*api.GenerateSequenceRequest).Length = 3
+	return nil
+}
+
+func (s stream) SendMsg(any) error {
+	return nil
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	var logs bytes.Buffer
+	l := New(slog.New(slog.NewJSONHandler(&logs, nil)), 1)
+
+	resp := &api.GenerateSequenceResponse{Sequence: []uint64{0, 1, 1}}
+	err := l.StreamInterceptor()(nil, stream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: method}, func(_ any, ss grpc.ServerStream) error {
+		var req api.GenerateSequenceRequest
+		require.NoError(t, ss.RecvMsg(&req))
+		require.NoError(t, ss.SendMsg(resp))
+		return ss.SendMsg(resp)
+	})
+	require.NoError(t, err)
+
+	logged := records(t, &logs)
+	require.Len(t, logged, 1)
+	require.Equal(t, "OK", logged[0]["code"])
+	require.Equal(t, float64(3), logged[0]["length"])
+	require.Equal(t, float64(2*proto.Size(resp)), logged[0]["response_size"])
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		status int
+		level  string
+	}{
+		"ok": {
+			status: http.StatusOK,
+			level:  "INFO",
+		},
+		"client error": {
+			status: http.StatusBadRequest,
+			level:  "WARN",
+		},
+		"server error": {
+			status: http.StatusServiceUnavailable,
+			level:  "ERROR",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var logs bytes.Buffer
+			l := New(slog.New(slog.NewJSONHandler(&logs, nil)), 1)
+
+			handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(`{"sequence":[]}`))
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=7", nil)
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			logged := records(t, &logs)
+			require.Len(t, logged, 1)
+			record := logged[0]
+			require.Equal(t, test.level, record["level"])
+			require.Equal(t, "http", record["protocol"])
+			require.Equal(t, "GET /api/v1/generate", record["method"])
+			require.Equal(t, r.RemoteAddr, record["peer"])
+			require.Equal(t, strconv.Itoa(test.status), record["code"])
+			require.NotContains(t, record, "length")
+			require.Equal(t, float64(len(`{"sequence":[]}`)), record["response_size"])
+		})
+	}
+}
@@ -0,0 +1,246 @@
+// Package admission protects the server from overload by bounding the cost of calls handled concurrently.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/domust/fibonacci/internal/auth"
+	"github.com/domust/fibonacci/internal/budget"
+	"github.com/domust/fibonacci/internal/ratelimit"
+	"github.com/domust/fibonacci/internal/telemetry"
+)
+
+// Default is the method name used for weights that apply to methods without explicit weights.
+const Default = "*"
+
+// Reasons of rejections, as reported in metrics.
+const (
+	ReasonQueueFull    = "queue_full"
+	ReasonQueueTimeout = "queue_timeout"
+	ReasonOverloaded   = "overloaded"
+)
+
+// latencyDecay is the weight of the latest observation in the moving average of latency.
+const latencyDecay = 0.1
+
+// Limits bound the work admitted by the controller.
+type Limits struct {
+	// MaxInFlight is the total cost of calls handled concurrently.
+	MaxInFlight int64
+	// MaxQueue is the number of calls allowed to wait for capacity, calls beyond it are rejected immediately.
+	MaxQueue int64
+	// QueueTimeout is how long calls wait for capacity before being rejected.
+	QueueTimeout time.Duration
+	// TargetLatency is the average latency above which calls are no longer queued, zero disables the signal.
+	TargetLatency time.Duration
+	// MaxGoroutines is the number of goroutines above which calls are no longer queued, zero disables the signal.
+	MaxGoroutines int
+	// Weights are per method costs of a call, or of a single requested element for requests with a length.
+	Weights map[string]float64
+}
+
+// ParseWeights parses comma separated weights in the form of "method=weight",
+// e.g. "/api.v1.Fibonacci/GenerateSequence=0.5,*=1".
+func ParseWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		method, weight, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("weight %q: missing method", entry)
+		}
+
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil || w <= 0 {
+			return nil, fmt.Errorf("weight %q: invalid weight", entry)
+		}
+
+		weights[method] = w
+	}
+
+	return weights, nil
+}
+
+// Controller admits calls while their total cost fits the limits and sheds load when the server is overloaded.
+type Controller struct {
+	limits     Limits
+	metrics    *telemetry.Metrics
+	goroutines func() int
+
+	sem     *semaphore.Weighted
+	waiting atomic.Int64
+
+	mu      sync.Mutex
+	latency time.Duration
+}
+
+// New returns controller enforcing the given limits, reporting queue depth and rejections to metrics.
+func New(limits Limits, metrics *telemetry.Metrics) *Controller {
+	return &Controller{
+		limits:     limits,
+		metrics:    metrics,
+		goroutines: runtime.NumGoroutine,
+		sem:        semaphore.NewWeighted(limits.MaxInFlight),
+	}
+}
+
+// Cost returns the cost of the call, which is weighted by the length of the sequence generated for it when known.
+func (c *Controller) Cost(method string, req any) int64 {
+	weight, ok := c.limits.Weights[method]
+	if !ok {
+		if weight, ok = c.limits.Weights[Default]; !ok {
+			weight = 1
+		}
+	}
+
+	if length, ok := budget.Length(req); ok {
+		weight *= float64(length)
+	}
+
+	// calls too large to ever fit are still admitted, although only on their own
+	return min(max(int64(math.Ceil(weight)), 1), c.limits.MaxInFlight)
+}
+
+// Overloaded reports whether either of the adaptive signals exceeds its threshold.
+func (c *Controller) Overloaded() bool {
+	if c.limits.MaxGoroutines > 0 && c.goroutines() > c.limits.MaxGoroutines {
+		return true
+	}
+
+	if c.limits.TargetLatency > 0 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		return c.latency > c.limits.TargetLatency
+	}
+
+	return false
+}
+
+// Acquire admits a call of the given cost, queueing it when capacity is not immediately available.
+// The returned function has to be called once the call completes.
+func (c *Controller) Acquire(ctx context.Context, method string, cost int64) (func(), error) {
+	if !c.sem.TryAcquire(cost) {
+		// queueing only adds latency to an overloaded server, so calls are shed right away
+		if c.Overloaded() {
+			return nil, c.reject(ctx, method, ReasonOverloaded)
+		}
+
+		if c.waiting.Add(1) > c.limits.MaxQueue {
+			c.waiting.Add(-1)
+			return nil, c.reject(ctx, method, ReasonQueueFull)
+		}
+		c.metrics.Queued(ctx, 1)
+
+		qctx, cancel := context.WithTimeout(ctx, c.limits.QueueTimeout)
+		err := c.sem.Acquire(qctx, cost)
+		cancel()
+
+		c.waiting.Add(-1)
+		c.metrics.Queued(ctx, -1)
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+			return nil, c.reject(ctx, method, ReasonQueueTimeout)
+		}
+	}
+
+	start := time.Now()
+	return func() {
+		c.observe(time.Since(start))
+		c.sem.Release(cost)
+	}, nil
+}
+
+// Queue returns the number of calls waiting for capacity.
+func (c *Controller) Queue() int64 {
+	return c.waiting.Load()
+}
+
+func (c *Controller) observe(latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.latency == 0 {
+		c.latency = latency
+		return
+	}
+	c.latency = time.Duration(latencyDecay*float64(latency) + (1-latencyDecay)*float64(c.latency))
+}
+
+// reject returns Unavailable with RetryInfo details and a retry-after header, which suggest retrying once
+// the queue would have been drained.
+func (c *Controller) reject(ctx context.Context, method, reason string) error {
+	c.metrics.Reject(ctx, method, reason)
+
+	delay := max(c.limits.QueueTimeout, time.Second)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(ratelimit.RetryAfterHeader, strconv.FormatInt(int64(math.Ceil(delay.Seconds())), 10)))
+
+	msg := "server overloaded: " + strings.ReplaceAll(reason, "_", " ")
+	st, err := status.New(codes.Unavailable, msg).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(delay),
+	})
+	if err != nil {
+		return status.Error(codes.Unavailable, msg)
+	}
+
+	return st.Err()
+}
+
+// UnaryInterceptor admits unary calls weighted by their requested length.
+// Calls of infrastructure, such as health checks, are always admitted, so that probes keep working under load.
+func (c *Controller) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if auth.Public(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		release, err := c.Acquire(ctx, info.FullMethod, c.Cost(info.FullMethod, req))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor admits streams for their whole lifetime, weighted by the method alone.
+// Streams of infrastructure, such as health watches, are always admitted without holding capacity.
+func (c *Controller) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if auth.Public(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		release, err := c.Acquire(ss.Context(), info.FullMethod, c.Cost(info.FullMethod, nil))
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,245 @@
+package admission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal/telemetry"
+)
+
+const method = "/api.v1.Fibonacci/GenerateSequence"
+
+// sum returns the total of the named int64 sum metric, optionally filtered by the reason attribute.
+func sum(t *testing.T, reader *sdkmetric.ManualReader, name, reason string) int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				if value, ok := dp.Attributes.Value("reason"); reason == "" || ok && value.AsString() == reason {
+					total += dp.Value
+				}
+			}
+		}
+	}
+
+	return total
+}
+
+func controller(t *testing.T, limits Limits) (*Controller, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	reader := sdkmetric.NewManualReader()
+	metrics, err := telemetry.NewMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+	require.NoError(t, err)
+
+	return New(limits, metrics), reader
+}
+
+func requireRejected(t *testing.T, err error) {
+	t.Helper()
+
+	s := status.Convert(err)
+	require.Equal(t, codes.Unavailable.String(), s.Code().String())
+	require.Len(t, s.Details(), 1)
+	info, ok := s.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Positive(t, info.GetRetryDelay().AsDuration())
+}
+
+func TestParseWeights(t *testing.T) {
+	weights, err := ParseWeights(method + "=0.5, *=2")
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{method: 0.5, Default: 2}, weights)
+
+	for _, value := range []string{"0.5", method + "=none", method + "=-1"} {
+		_, err := ParseWeights(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestCost(t *testing.T) {
+	tests := map[string]struct {
+		weights map[string]float64
+		req     any
+		cost    int64
+	}{
+		"unweighted": {
+			req:  &api.GenerateSequenceRequest{Length: 10},
+			cost: 10,
+		},
+		"method weight": {
+			weights: map[string]float64{method: 0.25},
+			req:     &api.GenerateSequenceRequest{Length: 10},
+			cost:    3,
+		},
+		"default weight": {
+			weights: map[string]float64{Default: 2},
+			req:     &api.GenerateSequenceRequest{Length: 10},
+			cost:    20,
+		},
+		"index": {
+			req:  &api.GetNumberRequest{Index: 9},
+			cost: 10,
+		},
+		"index at most capacity": {
+			req:  &api.GetNumberRequest{Index: 1000},
+			cost: 100,
+		},
+		"without length": {
+			weights: map[string]float64{Default: 2},
+			cost:    2,
+		},
+		"at least one": {
+			req:  &api.GenerateSequenceRequest{},
+			cost: 1,
+		},
+		"at most capacity": {
+			req:  &api.GenerateSequenceRequest{Length: 1000},
+			cost: 100,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := New(Limits{MaxInFlight: 100, Weights: data.weights}, nil)
+			require.Equal(t, data.cost, c.Cost(method, data.req))
+		})
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	t.Run("queue", func(t *testing.T) {
+		c, reader := controller(t, Limits{MaxInFlight: 10, MaxQueue: 1, QueueTimeout: time.Minute})
+		release, err := c.Acquire(context.Background(), method, 10)
+		require.NoError(t, err)
+
+		queued := make(chan error)
+		go func() {
+			release, err := c.Acquire(context.Background(), method, 5)
+			if err == nil {
+				release()
+			}
+			queued <- err
+		}()
+		require.Eventually(t, func() bool { return c.Queue() == 1 }, time.Second, time.Millisecond)
+		require.Equal(t, int64(1), sum(t, reader, "fibonacci.admission.queue", ""))
+
+		// the queue is full, so further calls are rejected right away
+		_, err = c.Acquire(context.Background(), method, 1)
+		requireRejected(t, err)
+		require.Equal(t, int64(1), sum(t, reader, "fibonacci.admission.rejections.count", ReasonQueueFull))
+
+		release()
+		require.NoError(t, <-queued)
+		require.Zero(t, c.Queue())
+		require.Zero(t, sum(t, reader, "fibonacci.admission.queue", ""))
+	})
+
+	t.Run("queue timeout", func(t *testing.T) {
+		c, reader := controller(t, Limits{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Millisecond})
+		release, err := c.Acquire(context.Background(), method, 1)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = c.Acquire(context.Background(), method, 1)
+		requireRejected(t, err)
+		require.Equal(t, int64(1), sum(t, reader, "fibonacci.admission.rejections.count", ReasonQueueTimeout))
+	})
+
+	t.Run("cancelled while queued", func(t *testing.T) {
+		c, reader := controller(t, Limits{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Minute})
+		release, err := c.Acquire(context.Background(), method, 1)
+		require.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		_, err = c.Acquire(ctx, method, 1)
+		require.Equal(t, codes.DeadlineExceeded.String(), status.Code(err).String())
+		require.Zero(t, sum(t, reader, "fibonacci.admission.rejections.count", ""))
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		c, reader := controller(t, Limits{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Minute, MaxGoroutines: 100})
+		c.goroutines = func() int { return 101 }
+
+		// calls are admitted while there is capacity, but never queued
+		release, err := c.Acquire(context.Background(), method, 1)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = c.Acquire(context.Background(), method, 1)
+		requireRejected(t, err)
+		require.Equal(t, int64(1), sum(t, reader, "fibonacci.admission.rejections.count", ReasonOverloaded))
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		c, _ := controller(t, Limits{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Minute, TargetLatency: time.Millisecond})
+		require.False(t, c.Overloaded())
+
+		release, err := c.Acquire(context.Background(), method, 1)
+		require.NoError(t, err)
+		time.Sleep(2 * time.Millisecond)
+		release()
+		require.True(t, c.Overloaded())
+	})
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	c, _ := controller(t, Limits{MaxInFlight: 10, QueueTimeout: time.Millisecond})
+
+	var inner error
+	resp, err := c.UnaryInterceptor()(context.Background(), &api.GenerateSequenceRequest{Length: 6}, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ any) (any, error) {
+		// the outer call holds 6 out of 10, which leaves no room for another one of the same size
+		_, inner = c.UnaryInterceptor()(ctx, &api.GenerateSequenceRequest{Length: 6}, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+			return nil, nil
+		})
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+	requireRejected(t, inner)
+
+	// capacity is released once calls complete
+	release, err := c.Acquire(context.Background(), method, 10)
+	require.NoError(t, err)
+	release()
+}
+
+func TestPublic(t *testing.T) {
+	c, _ := controller(t, Limits{MaxInFlight: 10, QueueTimeout: time.Millisecond})
+	release, err := c.Acquire(context.Background(), method, 10)
+	require.NoError(t, err)
+	t.Cleanup(release)
+
+	// health checks are admitted while the server is out of capacity
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	resp, err := c.UnaryInterceptor()(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+
+	err = c.StreamInterceptor()(nil, nil, &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}, func(any, grpc.ServerStream) error {
+		return nil
+	})
+	require.NoError(t, err)
+}
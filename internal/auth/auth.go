@@ -0,0 +1,258 @@
+// Package auth authenticates callers with static API keys or JWT bearer tokens.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyHeader is the metadata key carrying the API key of the client.
+const APIKeyHeader = "x-api-key"
+
+// AuthorizationHeader is the metadata key carrying the bearer token of the client.
+const AuthorizationHeader = "authorization"
+
+// algorithms are the signature algorithms accepted in bearer tokens.
+var algorithms = []jose.SignatureAlgorithm{jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.ES256, jose.ES384, jose.EdDSA}
+
+// public are method prefixes which do not require authentication, so that infrastructure keeps working.
+var public = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+// Public reports whether the method can be called without credentials.
+func Public(method string) bool {
+	for _, prefix := range public {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Principal is the authenticated caller.
+type Principal struct {
+	// Name is the name of the API key or the subject of the token.
+	Name string `json:"name"`
+	// Method is how the principal was authenticated, either "api-key" or "jwt".
+	Method string `json:"-"`
+	// Groups the principal belongs to.
+	Groups []string `json:"groups"`
+}
+
+type principalKey struct{}
+
+// NewContext returns a context carrying the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of the call, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Option configures the authenticator.
+type Option func(*Authenticator) error
+
+// WithAPIKeys loads API keys from a JSON file containing a list of objects with name, key and groups fields.
+func WithAPIKeys(path string) Option {
+	return func(a *Authenticator) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("api keys: %w", err)
+		}
+
+		var entries []struct {
+			Principal
+			Key string `json:"key"`
+		}
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("api keys: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.Name == "" || entry.Key == "" {
+				return errors.New("api keys: name and key are required")
+			}
+
+			p := entry.Principal
+			p.Method = "api-key"
+			// keys are looked up by their digest, so that lookup time does not depend on the secret
+			a.keys[sha256.Sum256([]byte(entry.Key))] = &p
+		}
+
+		return nil
+	}
+}
+
+// WithJWKS verifies bearer tokens against keys loaded from a local JWKS file,
+// requiring the given issuer and audience.
+func WithJWKS(path, issuer, audience string) Option {
+	return func(a *Authenticator) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("jwks: %w", err)
+		}
+
+		var jwks jose.JSONWebKeySet
+		if err := json.Unmarshal(data, &jwks); err != nil {
+			return fmt.Errorf("jwks: %w", err)
+		}
+
+		a.jwks = &jwks
+		a.issuer = issuer
+		a.audience = audience
+
+		return nil
+	}
+}
+
+// WithClock replaces the system clock used to verify token expiry, e.g. with a fake one in tests.
+func WithClock(now func() time.Time) Option {
+	return func(a *Authenticator) error {
+		a.now = now
+		return nil
+	}
+}
+
+// Authenticator identifies callers and rejects unauthenticated calls.
+type Authenticator struct {
+	keys     map[[sha256.Size]byte]*Principal
+	jwks     *jose.JSONWebKeySet
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// New returns authenticator accepting credentials configured by the given options.
+func New(opts ...Option) (*Authenticator, error) {
+	a := &Authenticator{
+		keys: make(map[[sha256.Size]byte]*Principal),
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Authenticate identifies the caller from the incoming metadata.
+func (a *Authenticator) Authenticate(ctx context.Context) (*Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if keys := md.Get(APIKeyHeader); len(keys) > 0 {
+		p, ok := a.keys[sha256.Sum256([]byte(keys[0]))]
+		if !ok {
+			return nil, errors.New("invalid api key")
+		}
+		return p, nil
+	}
+
+	if values := md.Get(AuthorizationHeader); len(values) > 0 {
+		scheme, token, ok := strings.Cut(values[0], " ")
+		if !ok || !strings.EqualFold(scheme, "bearer") {
+			return nil, errors.New("unsupported authorization scheme")
+		}
+		return a.verify(token)
+	}
+
+	return nil, errors.New("missing credentials")
+}
+
+func (a *Authenticator) verify(token string) (*Principal, error) {
+	if a.jwks == nil {
+		return nil, errors.New("bearer tokens are not accepted")
+	}
+
+	parsed, err := jwt.ParseSigned(token, algorithms)
+	if err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+
+	var (
+		claims jwt.Claims
+		custom struct {
+			Groups []string `json:"groups"`
+		}
+	)
+	if err := parsed.Claims(a.jwks, &claims, &custom); err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+
+	if claims.Expiry == nil {
+		return nil, errors.New("token: missing expiry")
+	}
+
+	err = claims.Validate(jwt.Expected{
+		Issuer:      a.issuer,
+		AnyAudience: jwt.Audience{a.audience},
+		Time:        a.now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+
+	return &Principal{Name: claims.Subject, Method: "jwt", Groups: custom.Groups}, nil
+}
+
+// UnaryInterceptor rejects unauthenticated calls and propagates the principal through the context.
+func (a *Authenticator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is the streaming counterpart of [Authenticator.UnaryInterceptor].
+func (a *Authenticator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		wrapped := middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+
+		return handler(srv, wrapped)
+	}
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if Public(method) {
+		return ctx, nil
+	}
+
+	p, err := a.Authenticate(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return NewContext(ctx, p), nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	issuer   = "https://issuer.example.com"
+	audience = "fibonacci"
+)
+
+// credentials writes API keys and a JWKS into temporary files and returns a token signer trusted by the JWKS.
+func credentials(t *testing.T) (keys, jwks string, signer jose.Signer) {
+	t.Helper()
+	dir := t.TempDir()
+
+	keys = filepath.Join(dir, "keys.json")
+	require.NoError(t, os.WriteFile(keys, []byte(`[{"name": "dashboard", "key": "secret", "groups": ["internal"]}]`), 0o600))
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	set, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &private.PublicKey, KeyID: "test", Algorithm: string(jose.ES256), Use: "sig"},
+	}})
+	require.NoError(t, err)
+
+	jwks = filepath.Join(dir, "jwks.json")
+	require.NoError(t, os.WriteFile(jwks, set, 0o600))
+
+	signer, err = jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: private}, (&jose.SignerOptions{}).WithHeader("kid", "test"))
+	require.NoError(t, err)
+
+	return keys, jwks, signer
+}
+
+func TestAuthenticator(t *testing.T) {
+	keys, jwks, signer := credentials(t)
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	a, err := New(WithAPIKeys(keys), WithJWKS(jwks, issuer, audience), WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	token := func(claims jwt.Claims) string {
+		groups := map[string]any{"groups": []string{"external"}}
+		raw, err := jwt.Signed(signer).Claims(claims).Claims(groups).Serialize()
+		require.NoError(t, err)
+		return "Bearer " + raw
+	}
+	valid := jwt.Claims{
+		Subject:  "alice",
+		Issuer:   issuer,
+		Audience: jwt.Audience{audience},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+	with := func(modify func(*jwt.Claims)) jwt.Claims {
+		claims := valid
+		modify(&claims)
+		return claims
+	}
+
+	tests := map[string]struct {
+		metadata  metadata.MD
+		principal *Principal
+	}{
+		"api key": {
+			metadata:  metadata.Pairs(APIKeyHeader, "secret"),
+			principal: &Principal{Name: "dashboard", Method: "api-key", Groups: []string{"internal"}},
+		},
+		"invalid api key": {
+			metadata: metadata.Pairs(APIKeyHeader, "guess"),
+		},
+		"bearer token": {
+			metadata:  metadata.Pairs(AuthorizationHeader, token(valid)),
+			principal: &Principal{Name: "alice", Method: "jwt", Groups: []string{"external"}},
+		},
+		"expired token": {
+			metadata: metadata.Pairs(AuthorizationHeader, token(with(func(c *jwt.Claims) {
+				c.Expiry = jwt.NewNumericDate(now.Add(-time.Hour))
+			}))),
+		},
+		"token without expiry": {
+			metadata: metadata.Pairs(AuthorizationHeader, token(with(func(c *jwt.Claims) {
+				c.Expiry = nil
+			}))),
+		},
+		"foreign issuer": {
+			metadata: metadata.Pairs(AuthorizationHeader, token(with(func(c *jwt.Claims) {
+				c.Issuer = "https://attacker.example.com"
+			}))),
+		},
+		"foreign audience": {
+			metadata: metadata.Pairs(AuthorizationHeader, token(with(func(c *jwt.Claims) {
+				c.Audience = jwt.Audience{"other"}
+			}))),
+		},
+		"untrusted signer": {
+			metadata: func() metadata.MD {
+				_, _, untrusted := credentials(t)
+				raw, err := jwt.Signed(untrusted).Claims(valid).Serialize()
+				require.NoError(t, err)
+				return metadata.Pairs(AuthorizationHeader, "Bearer "+raw)
+			}(),
+		},
+		"basic authorization": {
+			metadata: metadata.Pairs(AuthorizationHeader, "Basic ZGFzaGJvYXJkOnNlY3JldA=="),
+		},
+		"missing credentials": {},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := a.Authenticate(metadata.NewIncomingContext(context.Background(), data.metadata))
+			if data.principal == nil {
+				require.Error(t, err)
+				require.Nil(t, p)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, data.principal, p)
+		})
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	keys, _, _ := credentials(t)
+	a, err := New(WithAPIKeys(keys))
+	require.NoError(t, err)
+
+	interceptor := a.UnaryInterceptor()
+	handler := func(ctx context.Context, _ any) (any, error) {
+		p, _ := FromContext(ctx)
+		return p, nil
+	}
+
+	t.Run("propagates principal", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(APIKeyHeader, "secret"))
+		resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/api.v1.Fibonacci/GenerateSequence"}, handler)
+		require.NoError(t, err)
+		require.Equal(t, "dashboard", resp.(*Principal).Name)
+	})
+
+	t.Run("rejects anonymous", func(t *testing.T) {
+		resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/api.v1.Fibonacci/GenerateSequence"}, handler)
+		require.Equal(t, codes.Unauthenticated.String(), status.Code(err).String())
+		require.Nil(t, resp)
+	})
+
+	t.Run("health is public", func(t *testing.T) {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+		require.NoError(t, err)
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(WithAPIKeys(filepath.Join(t.TempDir(), "missing.json")))
+	require.ErrorContains(t, err, "api keys")
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name": "nameless"}]`), 0o600))
+	_, err = New(WithAPIKeys(path))
+	require.ErrorContains(t, err, "required")
+}
@@ -0,0 +1,227 @@
+// Package authz authorizes calls of authenticated principals against declarative policies.
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/domust/fibonacci/api"
+	apiv2 "github.com/domust/fibonacci/api/v2"
+	"github.com/domust/fibonacci/internal/auth"
+)
+
+// AnyMethod matches every method in [Policy.Methods].
+const AnyMethod = "*"
+
+// Policy allows calls of the listed methods when all of its conditions are met.
+// Conditions left empty are not evaluated.
+type Policy struct {
+	// Name identifies the policy in decision logs.
+	Name string `json:"name"`
+	// Methods are full method names the policy applies to, see [AnyMethod].
+	Methods []string `json:"methods"`
+	// Principals are names of principals allowed by the policy.
+	Principals []string `json:"principals"`
+	// Groups allow principals belonging to any of them.
+	Groups []string `json:"groups"`
+	// Condition is a CEL expression over principal, method and request variables, for example:
+	//
+	//	request.length <= 50 || "internal" in principal.groups
+	Condition string `json:"condition"`
+
+	program cel.Program
+}
+
+// Load reads a JSON list of policies from the file at the given path.
+func Load(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policies: %w", err)
+	}
+
+	var policies []Policy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// Option configures the authorizer.
+type Option func(*Authorizer)
+
+// WithLogger replaces the default logger used for decision logs.
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *Authorizer) {
+		a.logger = logger
+	}
+}
+
+// Authorizer denies calls not allowed by any of the policies.
+type Authorizer struct {
+	policies []Policy
+	logger   *slog.Logger
+}
+
+// New compiles conditions of the policies.
+func New(policies []Policy, opts ...Option) (*Authorizer, error) {
+	env, err := cel.NewEnv(
+		cel.TypeDescs(api.File_api_v1_api_proto, apiv2.File_api_v2_api_proto),
+		cel.CrossTypeNumericComparisons(true),
+		cel.Variable("principal", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("method", cel.StringType),
+		cel.Variable("request", cel.DynType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cel: %w", err)
+	}
+
+	a := &Authorizer{
+		policies: slices.Clone(policies),
+		logger:   slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	for i := range a.policies {
+		p := &a.policies[i]
+		if len(p.Methods) == 0 {
+			return nil, fmt.Errorf("policy %q: methods are required", p.Name)
+		}
+		if p.Condition == "" {
+			continue
+		}
+
+		ast, issues := env.Compile(p.Condition)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("policy %q: %w", p.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("policy %q: condition must evaluate to bool", p.Name)
+		}
+
+		if p.program, err = env.Program(ast); err != nil {
+			return nil, fmt.Errorf("policy %q: %w", p.Name, err)
+		}
+	}
+
+	return a, nil
+}
+
+// Authorize returns the name of the first policy allowing the call, or an error if there is none.
+func (a *Authorizer) Authorize(ctx context.Context, method string, req proto.Message) (string, error) {
+	principal, _ := auth.FromContext(ctx)
+	if principal == nil {
+		principal = &auth.Principal{}
+	}
+
+	vars := map[string]any{
+		"principal": map[string]any{
+			"name":   principal.Name,
+			"method": principal.Method,
+			"groups": principal.Groups,
+		},
+		"method":  method,
+		"request": req,
+	}
+
+	var errs []error
+	for _, p := range a.policies {
+		if !slices.Contains(p.Methods, method) && !slices.Contains(p.Methods, AnyMethod) {
+			continue
+		}
+		if len(p.Principals) > 0 && !slices.Contains(p.Principals, principal.Name) {
+			continue
+		}
+		if len(p.Groups) > 0 && !slices.ContainsFunc(p.Groups, func(g string) bool { return slices.Contains(principal.Groups, g) }) {
+			continue
+		}
+		if p.program == nil {
+			return p.Name, nil
+		}
+
+		out, _, err := p.program.ContextEval(ctx, vars)
+		if err != nil {
+			// evaluation errors deny by this policy only, so that other policies can still allow the call
+			errs = append(errs, fmt.Errorf("policy %q: %w", p.Name, err))
+			continue
+		}
+		if allowed, ok := out.Value().(bool); ok && allowed {
+			return p.Name, nil
+		}
+	}
+
+	return "", errors.Join(append([]error{errors.New("no policy allows the call")}, errs...)...)
+}
+
+// UnaryInterceptor denies calls with PermissionDenied unless they are allowed by a policy, logging every decision.
+func (a *Authorizer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := a.authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is the streaming counterpart of [Authorizer.UnaryInterceptor],
+// which authorizes every inbound message of the stream.
+func (a *Authorizer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &authorizedStream{ServerStream: ss, authorizer: a, method: info.FullMethod})
+	}
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+
+	authorizer *Authorizer
+	method     string
+}
+
+func (s *authorizedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return s.authorizer.authorize(s.Context(), s.method, m)
+}
+
+func (a *Authorizer) authorize(ctx context.Context, method string, req any) error {
+	if auth.Public(method) {
+		return nil
+	}
+
+	msg, _ := req.(proto.Message)
+	policy, err := a.Authorize(ctx, method, msg)
+
+	principal, _ := auth.FromContext(ctx)
+	attrs := []any{
+		slog.String("method", method),
+		slog.Bool("allowed", err == nil),
+	}
+	if principal != nil {
+		attrs = append(attrs, slog.String("principal", principal.Name))
+	}
+
+	if err != nil {
+		a.logger.WarnContext(ctx, "authorization denied", append(attrs, slog.String("reason", err.Error()))...)
+		return status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	a.logger.InfoContext(ctx, "authorization allowed", append(attrs, slog.String("policy", policy))...)
+	return nil
+}
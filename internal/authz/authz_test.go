@@ -0,0 +1,169 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal/auth"
+)
+
+const method = "/api.v1.Fibonacci/GenerateSequence"
+
+func TestAuthorizer(t *testing.T) {
+	a, err := New([]Policy{
+		{
+			Name:      "short sequences",
+			Methods:   []string{method},
+			Condition: "request.length <= 50",
+		},
+		{
+			Name:    "internal teams",
+			Methods: []string{AnyMethod},
+			Groups:  []string{"internal"},
+		},
+		{
+			Name:       "operators",
+			Methods:    []string{method},
+			Principals: []string{"operator"},
+			Condition:  `principal.method == "jwt"`,
+		},
+	})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		principal *auth.Principal
+		length    uint32
+		policy    string
+	}{
+		"anonymous short sequence": {
+			length: 10,
+			policy: "short sequences",
+		},
+		"anonymous long sequence": {
+			length: 90,
+		},
+		"external long sequence": {
+			principal: &auth.Principal{Name: "partner", Groups: []string{"external"}},
+			length:    90,
+		},
+		"internal long sequence": {
+			principal: &auth.Principal{Name: "dashboard", Groups: []string{"internal"}},
+			length:    90,
+			policy:    "internal teams",
+		},
+		"operator with token": {
+			principal: &auth.Principal{Name: "operator", Method: "jwt"},
+			length:    90,
+			policy:    "operators",
+		},
+		"operator with api key": {
+			principal: &auth.Principal{Name: "operator", Method: "api-key"},
+			length:    90,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if data.principal != nil {
+				ctx = auth.NewContext(ctx, data.principal)
+			}
+
+			policy, err := a.Authorize(ctx, method, &api.GenerateSequenceRequest{Length: data.length})
+			require.Equal(t, data.policy, policy)
+			if data.policy == "" {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := map[string]Policy{
+		"missing methods":   {Name: "policy"},
+		"invalid condition": {Name: "policy", Methods: []string{AnyMethod}, Condition: "request.length <="},
+		"non bool":          {Name: "policy", Methods: []string{AnyMethod}, Condition: "method"},
+		"unknown variable":  {Name: "policy", Methods: []string{AnyMethod}, Condition: "caller == 'me'"},
+	}
+
+	for name, policy := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New([]Policy{policy})
+			require.ErrorContains(t, err, `policy "policy"`)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name": "all", "methods": ["*"], "condition": "true"}]`), 0o600))
+
+	policies, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	require.Equal(t, "all", policies[0].Name)
+
+	_, err = New(policies)
+	require.NoError(t, err)
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	var logs bytes.Buffer
+	a, err := New([]Policy{{Name: "internal teams", Methods: []string{AnyMethod}, Groups: []string{"internal"}}},
+		WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))))
+	require.NoError(t, err)
+
+	interceptor := a.UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	// decision returns the last logged decision.
+	decision := func(t *testing.T) map[string]any {
+		lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(lines[len(lines)-1], &record))
+		return record
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		ctx := auth.NewContext(context.Background(), &auth.Principal{Name: "dashboard", Groups: []string{"internal"}})
+		resp, err := interceptor(ctx, &api.GenerateSequenceRequest{Length: 1}, info, handler)
+		require.NoError(t, err)
+		require.Equal(t, "ok", resp)
+
+		record := decision(t)
+		require.Equal(t, true, record["allowed"])
+		require.Equal(t, "dashboard", record["principal"])
+		require.Equal(t, "internal teams", record["policy"])
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		ctx := auth.NewContext(context.Background(), &auth.Principal{Name: "partner"})
+		resp, err := interceptor(ctx, &api.GenerateSequenceRequest{Length: 1}, info, handler)
+		require.Equal(t, codes.PermissionDenied.String(), status.Code(err).String())
+		require.Nil(t, resp)
+
+		record := decision(t)
+		require.Equal(t, false, record["allowed"])
+		require.Equal(t, "partner", record["principal"])
+		require.Equal(t, method, record["method"])
+	})
+
+	t.Run("health is public", func(t *testing.T) {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+		require.NoError(t, err)
+	})
+}
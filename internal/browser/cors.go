@@ -0,0 +1,198 @@
+// Package browser makes the gateway safe to call from web browsers, with CORS and security headers.
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/internal/gateway"
+)
+
+// AnyOrigin allows requests from any origin.
+const AnyOrigin = "*"
+
+// Methods and headers allowed by default, covering every route of the gateway.
+var (
+	DefaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	DefaultHeaders = []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "If-None-Match", "X-Api-Key"}
+	// DefaultExposedHeaders are response headers readable by scripts, in addition to the CORS-safelisted ones.
+	DefaultExposedHeaders = []string{"ETag", "Idempotent-Replayed", "Retry-After"}
+)
+
+// Policy describes which cross-origin requests are allowed.
+type Policy struct {
+	// Origins allowed to make requests, either exact origins such as "https://dashboard.example.com",
+	// origins with a wildcard subdomain such as "https://*.example.com", or [AnyOrigin].
+	Origins []string
+	// Methods allowed in requests, [DefaultMethods] when empty.
+	Methods []string
+	// Headers allowed in requests, [DefaultHeaders] when empty.
+	Headers []string
+	// ExposedHeaders are response headers readable by scripts, [DefaultExposedHeaders] when empty.
+	ExposedHeaders []string
+	// Credentials allows requests with cookies and authorization headers, which requires explicit origins.
+	Credentials bool
+	// MaxAge is how long browsers may cache results of preflight requests, browsers apply their own default when zero.
+	MaxAge time.Duration
+}
+
+// Validate reports policies that browsers would refuse to honor.
+func (p Policy) Validate() error {
+	if p.Credentials && slices.Contains(p.Origins, AnyOrigin) {
+		return errors.New("credentials cannot be allowed for any origin")
+	}
+
+	return nil
+}
+
+// CORS answers preflight requests and marks responses to allowed origins as readable by them.
+type CORS struct {
+	policy  Policy
+	methods string
+	headers string
+	exposed string
+	maxAge  string
+}
+
+// New returns CORS handling according to the policy.
+func New(policy Policy) *CORS {
+	if len(policy.Methods) == 0 {
+		policy.Methods = DefaultMethods
+	}
+	if len(policy.Headers) == 0 {
+		policy.Headers = DefaultHeaders
+	}
+	if len(policy.ExposedHeaders) == 0 {
+		policy.ExposedHeaders = DefaultExposedHeaders
+	}
+
+	c := &CORS{
+		policy:  policy,
+		methods: strings.Join(policy.Methods, ", "),
+		headers: strings.Join(policy.Headers, ", "),
+		exposed: strings.Join(policy.ExposedHeaders, ", "),
+	}
+	if policy.MaxAge > 0 {
+		c.maxAge = strconv.FormatInt(int64(policy.MaxAge.Seconds()), 10)
+	}
+
+	return c
+}
+
+// Middleware handles CORS in front of the next handler.
+// Preflight requests are answered directly, since the gateway has no routes for the OPTIONS method.
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		h := w.Header()
+		// responses differ by origin, so caches must not share them across origins
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !c.allowed(origin) {
+			if preflight {
+				forbidden(w, r, "origin %q is not allowed", origin)
+				return
+			}
+			// the response is served, but browsers do not let scripts read it
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c.allowOrigin(h, origin)
+
+		if !preflight {
+			h.Set("Access-Control-Expose-Headers", c.exposed)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if method := r.Header.Get("Access-Control-Request-Method"); !slices.Contains(c.policy.Methods, method) {
+			forbidden(w, r, "method %q is not allowed", method)
+			return
+		}
+		for header := range strings.SplitSeq(r.Header.Get("Access-Control-Request-Headers"), ",") {
+			if header = strings.TrimSpace(header); header != "" && !c.allowedHeader(header) {
+				forbidden(w, r, "header %q is not allowed", header)
+				return
+			}
+		}
+
+		h.Set("Access-Control-Allow-Methods", c.methods)
+		h.Set("Access-Control-Allow-Headers", c.headers)
+		if c.maxAge != "" {
+			h.Set("Access-Control-Max-Age", c.maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// allowed reports whether requests from the origin are allowed.
+func (c *CORS) allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.policy.Origins {
+		allowed = strings.ToLower(allowed)
+		if allowed == AnyOrigin || allowed == origin {
+			return true
+		}
+
+		// wildcards match subdomains only, so that "https://*.example.com" does not match "https://example.com"
+		scheme, domain, ok := strings.Cut(allowed, "://*.")
+		if ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *CORS) allowOrigin(h http.Header, origin string) {
+	if c.policy.Credentials {
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+		return
+	}
+
+	if slices.Contains(c.policy.Origins, AnyOrigin) {
+		h.Set("Access-Control-Allow-Origin", AnyOrigin)
+		return
+	}
+
+	h.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (c *CORS) allowedHeader(header string) bool {
+	for _, allowed := range c.policy.Headers {
+		if strings.EqualFold(allowed, header) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forbidden rejects preflight requests as problem details, although browsers only report the failure itself.
+func forbidden(w http.ResponseWriter, r *http.Request, format string, args ...any) {
+	problem := gateway.NewProblem(status.Newf(codes.PermissionDenied, format, args...), r.URL.Path)
+	w.Header().Set("Content-Type", gateway.ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,193 @@
+package browser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/domust/fibonacci/internal/gateway"
+)
+
+// ok responds to actual requests, which preflight requests never reach.
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Policy{Origins: []string{AnyOrigin}}.Validate())
+	require.NoError(t, Policy{Origins: []string{"https://dashboard.example.com"}, Credentials: true}.Validate())
+	require.Error(t, Policy{Origins: []string{AnyOrigin}, Credentials: true}.Validate())
+}
+
+func TestAllowed(t *testing.T) {
+	c := New(Policy{Origins: []string{"https://dashboard.example.com", "https://*.example.org"}})
+
+	tests := map[string]bool{
+		"https://dashboard.example.com": true,
+		"HTTPS://Dashboard.Example.com": true,
+		"https://api.example.org":       true,
+		"https://a.b.example.org":       true,
+		"https://example.org":           false,
+		"http://api.example.org":        false,
+		"https://evil-example.org":      false,
+		"https://example.com":           false,
+		"null":                          false,
+	}
+
+	for origin, allowed := range tests {
+		t.Run(origin, func(t *testing.T) {
+			require.Equal(t, allowed, c.allowed(origin))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	const origin = "https://dashboard.example.com"
+
+	tests := map[string]struct {
+		policy  Policy
+		method  string
+		origin  string
+		request http.Header
+		code    int
+		header  http.Header
+	}{
+		"same origin": {
+			policy: Policy{Origins: []string{origin}},
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			header: http.Header{"Vary": {"Origin"}},
+		},
+		"simple request": {
+			policy: Policy{Origins: []string{origin}},
+			method: http.MethodGet,
+			origin: origin,
+			code:   http.StatusOK,
+			header: http.Header{
+				"Access-Control-Allow-Origin":   {origin},
+				"Access-Control-Expose-Headers": {"ETag, Idempotent-Replayed, Retry-After"},
+				"Vary":                          {"Origin"},
+			},
+		},
+		"any origin": {
+			policy: Policy{Origins: []string{AnyOrigin}},
+			method: http.MethodGet,
+			origin: origin,
+			code:   http.StatusOK,
+			header: http.Header{
+				"Access-Control-Allow-Origin":   {AnyOrigin},
+				"Access-Control-Expose-Headers": {"ETag, Idempotent-Replayed, Retry-After"},
+				"Vary":                          {"Origin"},
+			},
+		},
+		"credentials": {
+			policy: Policy{Origins: []string{"https://*.example.com"}, Credentials: true},
+			method: http.MethodGet,
+			origin: origin,
+			code:   http.StatusOK,
+			header: http.Header{
+				"Access-Control-Allow-Origin":      {origin},
+				"Access-Control-Allow-Credentials": {"true"},
+				"Access-Control-Expose-Headers":    {"ETag, Idempotent-Replayed, Retry-After"},
+				"Vary":                             {"Origin"},
+			},
+		},
+		"disallowed origin": {
+			policy: Policy{Origins: []string{origin}},
+			method: http.MethodGet,
+			origin: "https://evil.example.com",
+			code:   http.StatusOK,
+			header: http.Header{"Vary": {"Origin"}},
+		},
+		"preflight": {
+			policy: Policy{Origins: []string{origin}, MaxAge: 10 * time.Minute},
+			method: http.MethodOptions,
+			origin: origin,
+			request: http.Header{
+				"Access-Control-Request-Method":  {http.MethodPost},
+				"Access-Control-Request-Headers": {"content-type, x-api-key"},
+			},
+			code: http.StatusNoContent,
+			header: http.Header{
+				"Access-Control-Allow-Origin":  {origin},
+				"Access-Control-Allow-Methods": {"GET, HEAD, POST"},
+				"Access-Control-Allow-Headers": {"Accept, Authorization, Content-Type, Idempotency-Key, If-None-Match, X-Api-Key"},
+				"Access-Control-Max-Age":       {"600"},
+				"Vary":                         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		"preflight of disallowed origin": {
+			policy:  Policy{Origins: []string{origin}},
+			method:  http.MethodOptions,
+			origin:  "https://evil.example.com",
+			request: http.Header{"Access-Control-Request-Method": {http.MethodGet}},
+			code:    http.StatusForbidden,
+			header: http.Header{
+				"Content-Type": {gateway.ProblemContentType},
+				"Vary":         {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		"preflight of disallowed method": {
+			policy:  Policy{Origins: []string{origin}},
+			method:  http.MethodOptions,
+			origin:  origin,
+			request: http.Header{"Access-Control-Request-Method": {http.MethodDelete}},
+			code:    http.StatusForbidden,
+			header: http.Header{
+				"Access-Control-Allow-Origin": {origin},
+				"Content-Type":                {gateway.ProblemContentType},
+				"Vary":                        {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		"preflight of disallowed header": {
+			policy: Policy{Origins: []string{origin}, Headers: []string{"Content-Type"}},
+			method: http.MethodOptions,
+			origin: origin,
+			request: http.Header{
+				"Access-Control-Request-Method":  {http.MethodGet},
+				"Access-Control-Request-Headers": {"authorization"},
+			},
+			code: http.StatusForbidden,
+			header: http.Header{
+				"Access-Control-Allow-Origin": {origin},
+				"Content-Type":                {gateway.ProblemContentType},
+				"Vary":                        {"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+			},
+		},
+		"options without preflight": {
+			policy: Policy{Origins: []string{origin}},
+			method: http.MethodOptions,
+			origin: origin,
+			code:   http.StatusNotImplemented,
+			header: http.Header{
+				"Access-Control-Allow-Origin":   {origin},
+				"Access-Control-Expose-Headers": {"ETag, Idempotent-Replayed, Retry-After"},
+				"Vary":                          {"Origin"},
+			},
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(data.method, "/api/v1/generate?length=5", nil)
+			for key, values := range data.request {
+				req.Header[key] = values
+			}
+			if data.origin != "" {
+				req.Header.Set("Origin", data.origin)
+			}
+			rec := httptest.NewRecorder()
+			New(data.policy).Middleware(ok).ServeHTTP(rec, req)
+
+			require.Equal(t, data.code, rec.Code)
+			require.Equal(t, data.header, rec.Header())
+		})
+	}
+}
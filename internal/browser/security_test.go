@@ -0,0 +1,29 @@
+package browser
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	rec := httptest.NewRecorder()
+	SecurityHeaders(ok).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	require.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+	require.Equal(t, "no-referrer", rec.Header().Get("Referrer-Policy"))
+	require.Contains(t, rec.Header().Get("Content-Security-Policy"), "frame-ancestors 'none'")
+	require.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+
+	// transport security is only meaningful for connections that are already secure
+	req.TLS = &tls.ConnectionState{}
+	rec = httptest.NewRecorder()
+	SecurityHeaders(ok).ServeHTTP(rec, req)
+	require.NotEmpty(t, rec.Header().Get("Strict-Transport-Security"))
+}
@@ -0,0 +1,141 @@
+// Package budget rejects calls whose estimated compute cost exceeds the time they are given.
+package budget
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apiv2 "github.com/domust/fibonacci/api/v2"
+)
+
+// bitsPerTerm is log2 of the golden ratio, which is the number of bits every term adds to the next one.
+const bitsPerTerm = 0.6942419136306174
+
+// Budget estimates the cost of calls and bounds the time they are allowed to take.
+type Budget struct {
+	perWord time.Duration
+	max     time.Duration
+}
+
+// New returns budget estimating the cost of an addition of a 64-bit word as perWord.
+// Calls estimated to take longer than max are rejected and are cancelled when they do, zero disables the limit.
+func New(perWord, max time.Duration) *Budget {
+	return &Budget{
+		perWord: perWord,
+		max:     max,
+	}
+}
+
+// Words returns the number of 64-bit word additions required to generate a sequence of the given length.
+func Words(length uint32) float64 {
+	n := float64(length)
+	// the i-th term spans i*bitsPerTerm bits, which sums up to a quadratic number of bits over the sequence,
+	// while even the smallest terms take a word
+	return n + bitsPerTerm*n*(n-1)/2/64
+}
+
+// Estimate returns how long generating a sequence of the given length is expected to take.
+func (b *Budget) Estimate(length uint32) time.Duration {
+	return time.Duration(min(Words(length)*float64(b.perWord), math.MaxInt64))
+}
+
+// lengthRequest is implemented by requests carrying the length of the sequence.
+type lengthRequest interface {
+	GetLength() uint32
+}
+
+// indexRequest is implemented by requests of a single term, which takes generating the sequence up to it.
+type indexRequest interface {
+	GetIndex() uint32
+}
+
+// Length returns the length of the sequence generated in order to serve the request, if known.
+func Length(req any) (uint32, bool) {
+	switch r := req.(type) {
+	case *apiv2.ListNumbersRequest:
+		// pages are generated from the first term, while page tokens are not validated with the rest of the request,
+		// so the length never exceeds the one served by v2
+		start, _ := apiv2.ParsePageToken(r.GetPageToken())
+		size := r.GetPageSize()
+		if size == 0 {
+			size = apiv2.DefaultPageSize
+		}
+		return uint32(min(uint64(start)+uint64(size), apiv2.MaxLength)), true
+	case lengthRequest:
+		return r.GetLength(), true
+	case indexRequest:
+		return min(r.GetIndex(), math.MaxUint32-1) + 1, true
+	}
+
+	return 0, false
+}
+
+// Check returns an error when the request cannot finish within the deadline of the context or the maximum budget.
+func (b *Budget) Check(ctx context.Context, req any) error {
+	length, ok := Length(req)
+	if !ok {
+		return nil
+	}
+
+	estimate := b.Estimate(length)
+	if b.max > 0 && estimate > b.max {
+		return status.Errorf(codes.ResourceExhausted, "estimated cost %s exceeds budget of %s", estimate, b.max)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); estimate > remaining {
+			return status.Errorf(codes.DeadlineExceeded, "estimated cost %s exceeds remaining deadline of %s", estimate, remaining.Round(time.Microsecond))
+		}
+	}
+
+	return nil
+}
+
+// limit bounds the context by the maximum budget.
+func (b *Budget) limit(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.max <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, b.max)
+}
+
+// UnaryInterceptor rejects calls exceeding the budget before they start and cancels the ones running over it.
+func (b *Budget) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := b.Check(ctx, req); err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := b.limit(ctx)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rejects messages exceeding the budget as they are received.
+func (b *Budget) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &stream{ServerStream: ss, budget: b})
+	}
+}
+
+type stream struct {
+	grpc.ServerStream
+
+	budget *Budget
+}
+
+func (s *stream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return s.budget.Check(s.Context(), m)
+}
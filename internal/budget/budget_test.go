@@ -0,0 +1,122 @@
+package budget
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/api"
+	apiv2 "github.com/domust/fibonacci/api/v2"
+)
+
+func TestWords(t *testing.T) {
+	require.Zero(t, Words(0))
+	require.Equal(t, float64(1), Words(1))
+	// the cost grows quadratically once terms span multiple words
+	require.Greater(t, Words(2000), 4*Words(1000)-2000)
+}
+
+func TestLength(t *testing.T) {
+	length, ok := Length(&api.GetNumberRequest{Index: 9})
+	require.True(t, ok)
+	require.Equal(t, uint32(10), length)
+
+	length, ok = Length(&apiv2.GetNumberRequest{Index: math.MaxUint32})
+	require.True(t, ok)
+	require.Equal(t, uint32(math.MaxUint32), length)
+
+	length, ok = Length(&apiv2.ListNumbersRequest{PageToken: apiv2.PageToken(100)})
+	require.True(t, ok)
+	require.Equal(t, uint32(100+apiv2.DefaultPageSize), length)
+
+	// lengths of pages starting past the last term are bounded, since page tokens are not validated
+	length, ok = Length(&apiv2.ListNumbersRequest{PageSize: 10, PageToken: apiv2.PageToken(math.MaxUint32)})
+	require.True(t, ok)
+	require.Equal(t, uint32(apiv2.MaxLength), length)
+
+	_, ok = Length(&api.GenerateSequenceResponse{})
+	require.False(t, ok)
+}
+
+func TestCheck(t *testing.T) {
+	b := New(time.Millisecond, time.Second)
+
+	tests := map[string]struct {
+		req     any
+		timeout time.Duration
+		code    codes.Code
+	}{
+		"within budget": {
+			req:  &api.GenerateSequenceRequest{Length: 10},
+			code: codes.OK,
+		},
+		"within deadline": {
+			req:     &api.GenerateSequenceRequest{Length: 10},
+			timeout: time.Minute,
+			code:    codes.OK,
+		},
+		"over budget": {
+			req:  &api.GenerateSequenceRequest{Length: 2000},
+			code: codes.ResourceExhausted,
+		},
+		"over deadline": {
+			req:     &api.GenerateSequenceRequest{Length: 94},
+			timeout: 10 * time.Millisecond,
+			code:    codes.DeadlineExceeded,
+		},
+		"index within budget": {
+			req:  &api.GetNumberRequest{Index: 9},
+			code: codes.OK,
+		},
+		"index over budget": {
+			req:  &apiv2.GetNumberRequest{Index: 1999},
+			code: codes.ResourceExhausted,
+		},
+		"without length": {
+			req:  &api.GenerateSequenceResponse{},
+			code: codes.OK,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if data.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, data.timeout)
+				defer cancel()
+			}
+
+			require.Equal(t, data.code.String(), status.Code(b.Check(ctx, data.req)).String())
+		})
+	}
+
+	t.Run("unlimited", func(t *testing.T) {
+		require.NoError(t, New(time.Millisecond, 0).Check(context.Background(), &api.GenerateSequenceRequest{Length: 2000}))
+	})
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	b := New(time.Nanosecond, time.Minute)
+
+	_, err := b.UnaryInterceptor()(context.Background(), &api.GenerateSequenceRequest{Length: 10}, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		// the handler is bounded by the maximum budget
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	_, err = New(time.Hour, time.Minute).UnaryInterceptor()(context.Background(), &api.GenerateSequenceRequest{Length: 10}, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+		t.Fatal("request over budget reached the handler")
+		return nil, nil
+	})
+	require.Equal(t, codes.ResourceExhausted.String(), status.Code(err).String())
+}
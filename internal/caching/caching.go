@@ -0,0 +1,100 @@
+// Package caching marks deterministic responses as immutable, so that clients and proxies can skip fetching them again.
+package caching
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/domust/fibonacci/internal/auth"
+)
+
+// Metadata keys of caching headers, which the gateway translates into the HTTP headers of the same name.
+const (
+	ETagHeader         = "etag"
+	CacheControlHeader = "cache-control"
+)
+
+// Cache derives entity tags from requests, since responses are fully determined by them.
+type Cache struct {
+	cacheControl        string
+	privateCacheControl string
+}
+
+// New returns cache allowing responses to be cached for maxAge.
+func New(maxAge time.Duration) *Cache {
+	return &Cache{
+		cacheControl:        fmt.Sprintf("public, max-age=%d, immutable", int64(maxAge.Seconds())),
+		privateCacheControl: fmt.Sprintf("private, max-age=%d, immutable", int64(maxAge.Seconds())),
+	}
+}
+
+// ETag returns a strong entity tag of the response to the normalized request of the given method.
+func ETag(method string, req proto.Message) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write(b)
+
+	return `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// UnaryInterceptor sets entity tag and cache control headers of successful responses.
+// Responses of calls made with credentials may only be cached by the client, since shared caches
+// would otherwise answer clients that were never authorized to make the call.
+func (c *Cache) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+
+		if msg, ok := req.(proto.Message); ok {
+			if etag, err := ETag(info.FullMethod, msg); err == nil {
+				cacheControl := c.cacheControl
+				if authenticated(ctx) {
+					cacheControl = c.privateCacheControl
+				}
+				_ = grpc.SetHeader(ctx, metadata.Pairs(ETagHeader, etag, CacheControlHeader, cacheControl))
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+// authenticated reports whether the call carries credentials, whether or not they were verified.
+func authenticated(ctx context.Context) bool {
+	if _, ok := auth.FromContext(ctx); ok {
+		return true
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+			return true
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	return len(md.Get(auth.AuthorizationHeader)) > 0 || len(md.Get(auth.APIKeyHeader)) > 0
+}
+
+// StreamInterceptor leaves streams as they are, since their responses depend on more than a single request.
+func (c *Cache) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, ss)
+	}
+}
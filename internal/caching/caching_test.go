@@ -0,0 +1,173 @@
+package caching
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal"
+	"github.com/domust/fibonacci/internal/auth"
+)
+
+func TestETag(t *testing.T) {
+	etag, err := ETag(api.Fibonacci_GenerateSequence_FullMethodName, &api.GenerateSequenceRequest{Length: 5})
+	require.NoError(t, err)
+	require.Regexp(t, `^"[A-Za-z0-9_-]{22}"$`, etag)
+
+	same, err := ETag(api.Fibonacci_GenerateSequence_FullMethodName, &api.GenerateSequenceRequest{Length: 5})
+	require.NoError(t, err)
+	require.Equal(t, etag, same)
+
+	length, err := ETag(api.Fibonacci_GenerateSequence_FullMethodName, &api.GenerateSequenceRequest{Length: 6})
+	require.NoError(t, err)
+	require.NotEqual(t, etag, length)
+
+	method, err := ETag(api.Fibonacci_GetNumber_FullMethodName, &api.GetNumberRequest{Index: 5})
+	require.NoError(t, err)
+	require.NotEqual(t, etag, method)
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(New(24 * time.Hour).UnaryInterceptor()))
+	api.RegisterFibonacciServer(s, internal.NewServer(nil))
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough://", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := api.NewFibonacciClient(conn)
+
+	var header metadata.MD
+	_, err = client.GenerateSequence(context.Background(), &api.GenerateSequenceRequest{Length: 5}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	etag, err := ETag(api.Fibonacci_GenerateSequence_FullMethodName, &api.GenerateSequenceRequest{Length: 5})
+	require.NoError(t, err)
+	require.Equal(t, []string{etag}, header.Get(ETagHeader))
+	require.Equal(t, []string{"public, max-age=86400, immutable"}, header.Get(CacheControlHeader))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyHeader, "secret")
+	_, err = client.GenerateSequence(ctx, &api.GenerateSequenceRequest{Length: 5}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{etag}, header.Get(ETagHeader))
+	require.Equal(t, []string{"private, max-age=86400, immutable"}, header.Get(CacheControlHeader))
+}
+
+func TestRepresentation(t *testing.T) {
+	tests := map[string]struct {
+		contentType     string
+		contentEncoding string
+		etag            string
+	}{
+		"json":            {contentType: "application/json", etag: `"abc"`},
+		"csv":             {contentType: "text/csv", etag: `"abc-csv"`},
+		"parameters":      {contentType: "text/plain; charset=utf-8", etag: `"abc-plain"`},
+		"protobuf":        {contentType: "application/x-protobuf", etag: `"abc-protobuf"`},
+		"compressed":      {contentType: "application/json", contentEncoding: "gzip", etag: `"abc-gzip"`},
+		"compressed text": {contentType: "text/csv", contentEncoding: "zstd", etag: `"abc-csv-zstd"`},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, data.etag, Representation(`"abc"`, data.contentType, data.contentEncoding))
+		})
+	}
+
+	require.Equal(t, `W/"abc"`, Representation(`W/"abc"`, "text/csv", ""))
+}
+
+func TestMatch(t *testing.T) {
+	require.True(t, Match(`"abc"`, `"abc"`))
+	require.True(t, Match(`"xyz", "abc"`, `"abc"`))
+	require.True(t, Match(`W/"abc"`, `"abc"`))
+	require.True(t, Match(`*`, `"abc"`))
+	require.False(t, Match(``, `"abc"`))
+	require.False(t, Match(`"abc-csv"`, `"abc"`))
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		w.Header().Set("Content-Type", r.Header.Get("Accept"))
+		_, _ = w.Write([]byte("0\n1\n"))
+	}))
+
+	tests := map[string]struct {
+		method      string
+		accept      string
+		ifNoneMatch string
+		code        int
+		etag        string
+	}{
+		"unconditional": {
+			method: http.MethodGet,
+			accept: "application/json",
+			code:   http.StatusOK,
+			etag:   `"abc"`,
+		},
+		"not modified": {
+			method:      http.MethodGet,
+			accept:      "application/json",
+			ifNoneMatch: `"abc"`,
+			code:        http.StatusNotModified,
+			etag:        `"abc"`,
+		},
+		"other representation": {
+			method:      http.MethodGet,
+			accept:      "text/plain",
+			ifNoneMatch: `"abc"`,
+			code:        http.StatusOK,
+			etag:        `"abc-plain"`,
+		},
+		"same representation": {
+			method:      http.MethodGet,
+			accept:      "text/plain",
+			ifNoneMatch: `"abc-plain"`,
+			code:        http.StatusNotModified,
+			etag:        `"abc-plain"`,
+		},
+		"post": {
+			method:      http.MethodPost,
+			accept:      "application/json",
+			ifNoneMatch: `"abc"`,
+			code:        http.StatusOK,
+			etag:        `"abc"`,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(data.method, "/api/v1/sequences/2", nil)
+			req.Header.Set("Accept", data.accept)
+			req.Header.Set("If-None-Match", data.ifNoneMatch)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, data.code, rec.Code)
+			require.Equal(t, data.etag, rec.Header().Get("ETag"))
+			require.Equal(t, []string{"Accept", "Authorization", "X-Api-Key"}, rec.Header().Values("Vary"))
+			if data.code == http.StatusNotModified {
+				require.Empty(t, rec.Body.String())
+				require.Empty(t, rec.Header().Get("Content-Type"))
+			} else {
+				require.Equal(t, "0\n1\n", rec.Body.String())
+			}
+		})
+	}
+}
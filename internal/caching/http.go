@@ -0,0 +1,131 @@
+package caching
+
+import (
+	"bufio"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Middleware answers conditional requests for unchanged representations with 304 Not Modified.
+// Entity tags set by the grpc server are extended with the format and coding of the representation,
+// so that each representation of the same response has a distinct strong entity tag. Shared caches are told
+// to key responses on the negotiated format, as well as on credentials, since responses to calls made with them are private.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional := r.Method == http.MethodGet || r.Method == http.MethodHead
+		next.ServeHTTP(&writer{ResponseWriter: w, ifNoneMatch: r.Header.Get("If-None-Match"), conditional: conditional}, r)
+	})
+}
+
+// writer replaces successful responses with 304 Not Modified when their entity tag matches.
+type writer struct {
+	http.ResponseWriter
+
+	ifNoneMatch string
+	conditional bool
+
+	wroteHeader bool
+	discard     bool
+}
+
+func (w *writer) WriteHeader(status int) {
+	if w.wroteHeader || status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	etag := h.Get("ETag")
+	if status != http.StatusOK || etag == "" {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	etag = Representation(etag, h.Get("Content-Type"), h.Get("Content-Encoding"))
+	h.Set("ETag", etag)
+	h.Add("Vary", "Accept")
+	h.Add("Vary", "Authorization")
+	h.Add("Vary", "X-Api-Key")
+
+	if w.conditional && Match(w.ifNoneMatch, etag) {
+		w.discard = true
+		for _, key := range []string{"Content-Type", "Content-Length", "Content-Encoding"} {
+			h.Del(key)
+		}
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush supports streaming responses.
+func (w *writer) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.discard {
+		f.Flush()
+	}
+}
+
+// Hijack supports protocol upgrades, such as WebSockets.
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("hijacking is not supported")
+}
+
+// Unwrap allows [http.ResponseController] to reach the underlying writer.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Representation extends the entity tag of a response with the media type and coding of its representation.
+// JSON, which is the default representation, is left as is.
+func Representation(etag, contentType, contentEncoding string) string {
+	tag, ok := strings.CutPrefix(etag, `"`)
+	if !ok {
+		return etag // weak or malformed tags are left alone
+	}
+	tag = strings.TrimSuffix(tag, `"`)
+
+	if media, _, err := mime.ParseMediaType(contentType); err == nil && media != "application/json" {
+		_, subtype, _ := strings.Cut(media, "/")
+		tag += "-" + strings.TrimPrefix(subtype, "x-")
+	}
+	if contentEncoding != "" {
+		tag += "-" + contentEncoding
+	}
+
+	return `"` + tag + `"`
+}
+
+// Match reports whether the If-None-Match header matches the entity tag, using weak comparison.
+func Match(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,206 @@
+// Package compression negotiates compression of large responses on both grpc and REST.
+package compression
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/domust/fibonacci/internal/telemetry"
+)
+
+// Supported content codings, in order of preference.
+const (
+	Zstd = "zstd"
+	Gzip = gzip.Name
+)
+
+// base is the gzip compressor registered by grpc, which is kept in order to be instrumented.
+var base = encoding.GetCompressor(Gzip)
+
+// Compression compresses responses of at least threshold bytes and records how much they shrink.
+type Compression struct {
+	threshold int
+	metrics   *telemetry.Metrics
+}
+
+// New returns compression of responses of at least threshold bytes.
+func New(threshold int, metrics *telemetry.Metrics) *Compression {
+	return &Compression{
+		threshold: threshold,
+		metrics:   metrics,
+	}
+}
+
+// Register registers instrumented gzip and zstd compressors with grpc.
+// It must only be called during initialization, since compressor registration is not thread-safe.
+func (c *Compression) Register() {
+	encoding.RegisterCompressor(&instrumented{Compressor: base, metrics: c.metrics})
+	encoding.RegisterCompressor(&instrumented{Compressor: &zstdCompressor{}, metrics: c.metrics})
+}
+
+// UnaryInterceptor leaves responses smaller than the threshold uncompressed.
+func (c *Compression) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			c.decide(ctx, resp)
+		}
+
+		return resp, err
+	}
+}
+
+// StreamInterceptor leaves streams uncompressed when their first response is smaller than the threshold,
+// since the compressor is announced in headers sent along with it.
+func (c *Compression) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &stream{ServerStream: ss, compression: c})
+	}
+}
+
+type stream struct {
+	grpc.ServerStream
+
+	compression *Compression
+	sent        bool
+}
+
+func (s *stream) SendMsg(m any) error {
+	if !s.sent {
+		s.sent = true
+		s.compression.decide(s.Context(), m)
+	}
+
+	return s.ServerStream.SendMsg(m)
+}
+
+func (c *Compression) decide(ctx context.Context, resp any) {
+	if m, ok := resp.(proto.Message); ok && proto.Size(m) < c.threshold {
+		// fails only outside of grpc handlers, e.g. in tests, where there is nothing to compress anyway
+		_ = grpc.SetSendCompressor(ctx, encoding.Identity)
+	}
+}
+
+// instrumented records the compression ratio of every compressed message.
+type instrumented struct {
+	encoding.Compressor
+
+	metrics *telemetry.Metrics
+}
+
+func (c *instrumented) Compress(w io.Writer) (io.WriteCloser, error) {
+	counted := &counter{Writer: w}
+	wc, err := c.Compressor.Compress(counted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &measured{WriteCloser: wc, compressed: counted, done: func(uncompressed, compressed int) {
+		c.metrics.Compressed(context.Background(), "grpc", c.Name(), uncompressed, compressed)
+	}}, nil
+}
+
+// counter counts bytes written through it.
+type counter struct {
+	io.Writer
+
+	n int
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.n += n
+	return n, err
+}
+
+// measured reports the number of bytes written before and after compression once closed.
+type measured struct {
+	io.WriteCloser
+
+	uncompressed int
+	compressed   *counter
+	done         func(uncompressed, compressed int)
+}
+
+func (m *measured) Write(p []byte) (int, error) {
+	n, err := m.WriteCloser.Write(p)
+	m.uncompressed += n
+	return n, err
+}
+
+func (m *measured) Close() error {
+	err := m.WriteCloser.Close()
+	m.done(m.uncompressed, m.compressed.n)
+	return err
+}
+
+// zstdCompressor implements zstd for grpc, pooling encoders and decoders since they are expensive to create.
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	enc, ok := c.encoders.Get().(*zstd.Encoder)
+	if !ok {
+		var err error
+		if enc, err = zstd.NewWriter(w, zstd.WithEncoderConcurrency(1)); err != nil {
+			return nil, err
+		}
+	} else {
+		enc.Reset(w)
+	}
+
+	return &zstdWriter{Encoder: enc, pool: &c.encoders}, nil
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	defer w.pool.Put(w.Encoder)
+	return w.Encoder.Close()
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	dec, ok := c.decoders.Get().(*zstd.Decoder)
+	if !ok {
+		var err error
+		if dec, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1)); err != nil {
+			return nil, err
+		}
+	} else if err := dec.Reset(r); err != nil {
+		c.decoders.Put(dec)
+		return nil, err
+	}
+
+	return &zstdReader{Decoder: dec, pool: &c.decoders}, nil
+}
+
+type zstdReader struct {
+	*zstd.Decoder
+
+	pool *sync.Pool
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	n, err := r.Decoder.Read(p)
+	if err == io.EOF {
+		r.pool.Put(r.Decoder)
+	}
+	return n, err
+}
+
+func (c *zstdCompressor) Name() string {
+	return Zstd
+}
@@ -0,0 +1,217 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal"
+	"github.com/domust/fibonacci/internal/telemetry"
+)
+
+// compressed returns the number of compressed messages per protocol and encoding.
+func compressed(t *testing.T, reader *sdkmetric.ManualReader) map[string]uint64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	counts := make(map[string]uint64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "fibonacci.compression.ratio" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				protocol, _ := dp.Attributes.Value("protocol")
+				coding, _ := dp.Attributes.Value("encoding")
+				counts[protocol.AsString()+"/"+coding.AsString()] += dp.Count
+			}
+		}
+	}
+
+	return counts
+}
+
+// saved returns bytes saved by compression per protocol and encoding.
+func saved(t *testing.T, reader *sdkmetric.ManualReader) map[string]int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	totals := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "fibonacci.compression.saved" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				protocol, _ := dp.Attributes.Value("protocol")
+				coding, _ := dp.Attributes.Value("encoding")
+				totals[protocol.AsString()+"/"+coding.AsString()] += dp.Value
+			}
+		}
+	}
+
+	return totals
+}
+
+func compression(t *testing.T) (*Compression, *sdkmetric.ManualReader) {
+	t.Helper()
+
+	reader := sdkmetric.NewManualReader()
+	metrics, err := telemetry.NewMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+	require.NoError(t, err)
+
+	return New(100, metrics), reader
+}
+
+func TestGRPC(t *testing.T) {
+	c, reader := compression(t)
+	c.Register()
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(c.UnaryInterceptor()))
+	api.RegisterFibonacciServer(s, internal.NewServer(nil))
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough://", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := api.NewFibonacciClient(conn)
+
+	for _, coding := range []string{Gzip, Zstd} {
+		t.Run(coding, func(t *testing.T) {
+			// the client shares compressors with the server, so its requests are measured as well
+			before := compressed(t, reader)["grpc/"+coding]
+			resp, err := client.GenerateSequence(context.Background(), &api.GenerateSequenceRequest{Length: 94}, grpc.UseCompressor(coding))
+			require.NoError(t, err)
+			require.Len(t, resp.GetSequence(), 94)
+			require.Equal(t, before+2, compressed(t, reader)["grpc/"+coding])
+
+			// small responses are sent as is
+			resp, err = client.GenerateSequence(context.Background(), &api.GenerateSequenceRequest{Length: 3}, grpc.UseCompressor(coding))
+			require.NoError(t, err)
+			require.Equal(t, []uint64{0, 1, 1}, resp.GetSequence())
+			require.Equal(t, before+3, compressed(t, reader)["grpc/"+coding])
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"identity":                "",
+		"gzip":                    Gzip,
+		"gzip, deflate, br, zstd": Zstd,
+		"zstd;q=0.5, gzip":        Gzip,
+		"*":                       Zstd,
+		"zstd;q=0, *":             Gzip,
+		"GZIP;q=0.1":              Gzip,
+		"gzip;q=0":                "",
+	}
+
+	for header, coding := range tests {
+		t.Run(header, func(t *testing.T) {
+			require.Equal(t, coding, Negotiate(header))
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"sequence":["0","1","1","2","3"]}`, 10)
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		Gzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		Zstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	tests := map[string]struct {
+		accept   string
+		body     string
+		encoding string
+		coding   string
+	}{
+		"gzip": {
+			accept: "gzip",
+			body:   large,
+			coding: Gzip,
+		},
+		"zstd": {
+			accept: "gzip, zstd",
+			body:   large,
+			coding: Zstd,
+		},
+		"below threshold": {
+			accept: "gzip",
+			body:   `{"sequence":[]}`,
+		},
+		"not accepted": {
+			body: large,
+		},
+		"already encoded": {
+			accept:   "gzip",
+			body:     large,
+			encoding: "br",
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, reader := compression(t)
+			handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if data.encoding != "" {
+					w.Header().Set("Content-Encoding", data.encoding)
+				}
+				w.WriteHeader(http.StatusOK)
+				// written in chunks in order to cross the threshold midway
+				for chunk := range strings.SplitAfterSeq(data.body, "}") {
+					_, _ = w.Write([]byte(chunk))
+				}
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=5", nil)
+			req.Header.Set("Accept-Encoding", data.accept)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+			if data.coding == "" {
+				require.Equal(t, data.encoding, rec.Header().Get("Content-Encoding"))
+				require.Equal(t, data.body, rec.Body.String())
+				require.Empty(t, saved(t, reader))
+				return
+			}
+
+			require.Equal(t, data.coding, rec.Header().Get("Content-Encoding"))
+			require.Less(t, rec.Body.Len(), len(data.body))
+			r, err := decoders[data.coding](bytes.NewReader(rec.Body.Bytes()))
+			require.NoError(t, err)
+			body, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, data.body, string(body))
+			require.Equal(t, int64(len(data.body)-rec.Body.Len()), saved(t, reader)["http/"+data.coding])
+		})
+	}
+}
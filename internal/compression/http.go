@@ -0,0 +1,233 @@
+package compression
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	zstdWriters = sync.Pool{New: func() any {
+		enc, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+		return enc
+	}}
+)
+
+// encoder is a compressing writer, which can be flushed for streaming responses.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// Negotiate returns the preferred supported coding acceptable according to the Accept-Encoding header,
+// or an empty string when the response should not be compressed.
+func Negotiate(header string) string {
+	var (
+		best    string
+		quality float64
+	)
+	for _, coding := range []string{Zstd, Gzip} {
+		if q := acceptable(header, coding); q > quality {
+			best, quality = coding, q
+		}
+	}
+
+	return best
+}
+
+// acceptable returns the quality assigned to the coding, either explicitly or by a wildcard.
+func acceptable(header, coding string) float64 {
+	wildcard := 0.0
+	for entry := range strings.SplitSeq(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != coding && name != "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == coding {
+			return q
+		}
+		wildcard = q
+	}
+
+	return wildcard
+}
+
+// Middleware compresses HTTP responses of at least threshold bytes with the coding negotiated with the client.
+func (c *Compression) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		coding := Negotiate(r.Header.Get("Accept-Encoding"))
+		if coding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, compression: c, coding: coding, request: r, status: http.StatusOK}
+		defer cw.close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter buffers the response until it reaches the threshold, after which it is compressed.
+type compressWriter struct {
+	http.ResponseWriter
+
+	compression *Compression
+	coding      string
+	request     *http.Request
+
+	status  int
+	buf     []byte
+	decided bool
+
+	enc          encoder
+	compressed   *counter
+	uncompressed int
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided || status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.status = status
+	// responses without a body or with a coding of their own are never compressed
+	if status == http.StatusNoContent || status == http.StatusNotModified || w.Header().Get("Content-Encoding") != "" {
+		w.start(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		if w.Header().Get("Content-Encoding") != "" {
+			w.start(false)
+		} else {
+			w.buf = append(w.buf, p...)
+			if len(w.buf) >= w.compression.threshold {
+				if err := w.start(true); err != nil {
+					return 0, err
+				}
+			}
+			return len(p), nil
+		}
+	}
+
+	if w.enc != nil {
+		n, err := w.enc.Write(p)
+		w.uncompressed += n
+		return n, err
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// start writes the header and the buffered body, either compressed or as is.
+func (w *compressWriter) start(compress bool) error {
+	w.decided = true
+
+	if !compress {
+		w.ResponseWriter.WriteHeader(w.status)
+		if len(w.buf) == 0 {
+			return nil
+		}
+		_, err := w.ResponseWriter.Write(w.buf)
+		return err
+	}
+
+	w.Header().Set("Content-Encoding", w.coding)
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.compressed = &counter{Writer: w.ResponseWriter}
+	switch w.coding {
+	case Zstd:
+		enc := zstdWriters.Get().(*zstd.Encoder)
+		enc.Reset(w.compressed)
+		w.enc = enc
+	default:
+		enc := gzipWriters.Get().(*gzip.Writer)
+		enc.Reset(w.compressed)
+		w.enc = enc
+	}
+
+	n, err := w.enc.Write(w.buf)
+	w.uncompressed += n
+	w.buf = nil
+	return err
+}
+
+// Flush starts compression right away, since flushed responses are streamed and their size is unknown.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.start(true)
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack supports protocol upgrades, such as WebSockets, whose frames are never compressed by the middleware.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok || w.decided {
+		return nil, nil, errors.New("hijacking is not supported")
+	}
+
+	w.decided = true
+	return h.Hijack()
+}
+
+// Unwrap allows [http.ResponseController] to reach the underlying writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the response, which is sent as is when it never reached the threshold.
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.start(false)
+		return
+	}
+
+	if w.enc == nil {
+		return
+	}
+
+	_ = w.enc.Close()
+	switch enc := w.enc.(type) {
+	case *zstd.Encoder:
+		enc.Reset(io.Discard)
+		zstdWriters.Put(enc)
+	case *gzip.Writer:
+		enc.Reset(io.Discard)
+		gzipWriters.Put(enc)
+	}
+
+	w.compression.metrics.Compressed(w.request.Context(), "http", w.coding, w.uncompressed, w.compressed.n)
+}
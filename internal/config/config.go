@@ -0,0 +1,265 @@
+// Package config loads service configuration from the environment.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/domust/fibonacci/internal/admission"
+	"github.com/domust/fibonacci/internal/browser"
+	"github.com/domust/fibonacci/internal/gateway"
+	"github.com/domust/fibonacci/internal/httpserver"
+	"github.com/domust/fibonacci/internal/ratelimit"
+)
+
+// Config holds settings that can be tuned per deployment.
+type Config struct {
+	// Reflection exposes grpc server reflection and the descriptor set over HTTP.
+	Reflection bool
+	// RateLimits are per-client limits keyed by full method name, rate limiting is disabled when empty.
+	RateLimits map[string]ratelimit.Limit
+	// Admission bounds the cost of calls handled concurrently, admission control is disabled when MaxInFlight is zero.
+	Admission admission.Limits
+	// CostPerWord is the estimated cost of adding a pair of 64-bit words while generating a sequence.
+	CostPerWord time.Duration
+	// MaxBudget is the estimated cost above which calls are rejected, the budget is unlimited when zero.
+	MaxBudget time.Duration
+	// CompressionThreshold is the size in bytes below which responses are not compressed.
+	CompressionThreshold int64
+	// CacheMaxAge is how long responses may be cached, caching headers are omitted when zero.
+	CacheMaxAge time.Duration
+	// AuthAPIKeys is the path to a JSON file with API keys.
+	AuthAPIKeys string
+	// AuthJWKS is the path to a JWKS file with keys trusted to sign bearer tokens.
+	AuthJWKS string
+	// AuthIssuer is the required issuer of bearer tokens.
+	AuthIssuer string
+	// AuthAudience is the required audience of bearer tokens.
+	AuthAudience string
+	// AuthzPolicies is the path to a JSON file with authorization policies.
+	AuthzPolicies string
+	// AccessLog emits a structured record for every grpc call and HTTP request.
+	AccessLog bool
+	// AccessLogSampleRate is the fraction of successful calls that are logged, failed calls are always logged.
+	AccessLogSampleRate float64
+	// AccessLogRedact lists access log fields whose values are redacted.
+	AccessLogRedact []string
+	// HealthInterval is how often health checks are evaluated.
+	HealthInterval time.Duration
+	// HealthTimeout is how long a single health check is allowed to take.
+	HealthTimeout time.Duration
+	// ShutdownDrain is how long the server keeps serving after reporting NOT_SERVING on shutdown.
+	ShutdownDrain time.Duration
+	// ShutdownTimeout is how long listeners are given to finish in-flight requests before being closed.
+	ShutdownTimeout time.Duration
+	// HTTP bounds the resources held by clients of the gateway.
+	HTTP httpserver.Limits
+	// CORS allows browsers to call the gateway from other origins, CORS is disabled when no origins are allowed.
+	CORS browser.Policy
+	// SecurityHeaders protects browsers from sniffing, framing and leaking gateway responses.
+	SecurityHeaders bool
+	// WebSocket tunes connections streaming sequences to browsers, which are allowed from the same origins as CORS.
+	WebSocket gateway.WebSocketOptions
+	// EventStream paces server-sent events, e.g. in order to turn the stream into a Fibonacci clock.
+	EventStream gateway.EventStreamOptions
+	// MCPStdio serves MCP tools over stdin and stdout instead of starting the grpc server and the gateway.
+	MCPStdio bool
+	// IdempotencyTTL is how long outcomes of calls carrying an idempotency key are stored, keys are ignored when zero.
+	IdempotencyTTL time.Duration
+}
+
+// Load reads configuration from environment variables prefixed with FIBONACCI_.
+func Load() (*Config, error) {
+	var env env
+
+	cfg := &Config{
+		Reflection: env.bool("FIBONACCI_REFLECTION", false),
+		RateLimits: env.rateLimits("FIBONACCI_RATE_LIMITS"),
+		Admission: admission.Limits{
+			MaxInFlight:   env.int("FIBONACCI_MAX_IN_FLIGHT", 4096),
+			MaxQueue:      env.int("FIBONACCI_MAX_QUEUE", 256),
+			QueueTimeout:  env.duration("FIBONACCI_QUEUE_TIMEOUT", time.Second),
+			TargetLatency: env.duration("FIBONACCI_TARGET_LATENCY", 0),
+			MaxGoroutines: int(env.int("FIBONACCI_MAX_GOROUTINES", 0)),
+			Weights:       env.weights("FIBONACCI_ADMISSION_WEIGHTS"),
+		},
+		CostPerWord:          env.duration("FIBONACCI_COST_PER_WORD", 10*time.Nanosecond),
+		MaxBudget:            env.duration("FIBONACCI_MAX_BUDGET", time.Second),
+		CompressionThreshold: env.int("FIBONACCI_COMPRESSION_THRESHOLD", 1024),
+		CacheMaxAge:          env.duration("FIBONACCI_CACHE_MAX_AGE", 365*24*time.Hour),
+		AuthAPIKeys:          os.Getenv("FIBONACCI_AUTH_API_KEYS"),
+		AuthJWKS:             os.Getenv("FIBONACCI_AUTH_JWKS"),
+		AuthIssuer:           os.Getenv("FIBONACCI_AUTH_ISSUER"),
+		AuthAudience:         os.Getenv("FIBONACCI_AUTH_AUDIENCE"),
+		AuthzPolicies:        os.Getenv("FIBONACCI_AUTHZ_POLICIES"),
+		AccessLog:            env.bool("FIBONACCI_ACCESS_LOG", true),
+		AccessLogSampleRate:  env.float("FIBONACCI_ACCESS_LOG_SAMPLE_RATE", 1),
+		AccessLogRedact:      env.list("FIBONACCI_ACCESS_LOG_REDACT"),
+		HealthInterval:       env.duration("FIBONACCI_HEALTH_INTERVAL", 10*time.Second),
+		HealthTimeout:        env.duration("FIBONACCI_HEALTH_TIMEOUT", 2*time.Second),
+		ShutdownDrain:        env.duration("FIBONACCI_SHUTDOWN_DRAIN", 5*time.Second),
+		ShutdownTimeout:      env.duration("FIBONACCI_SHUTDOWN_TIMEOUT", 10*time.Second),
+		HTTP: httpserver.Limits{
+			ReadHeaderTimeout: env.duration("FIBONACCI_HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+			ReadTimeout:       env.duration("FIBONACCI_HTTP_READ_TIMEOUT", 30*time.Second),
+			WriteTimeout:      env.duration("FIBONACCI_HTTP_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:       env.duration("FIBONACCI_HTTP_IDLE_TIMEOUT", 2*time.Minute),
+			MaxHeaderBytes:    int(env.int("FIBONACCI_HTTP_MAX_HEADER_BYTES", 16<<10)),
+			MaxBodyBytes:      env.int("FIBONACCI_HTTP_MAX_BODY_BYTES", 1<<20),
+			MaxConns:          int(env.int("FIBONACCI_HTTP_MAX_CONNS", 10000)),
+			MaxConnsPerClient: int(env.int("FIBONACCI_HTTP_MAX_CONNS_PER_CLIENT", 0)),
+		},
+		CORS: browser.Policy{
+			Origins:     env.list("FIBONACCI_CORS_ORIGINS"),
+			Headers:     env.list("FIBONACCI_CORS_HEADERS"),
+			Credentials: env.bool("FIBONACCI_CORS_CREDENTIALS", false),
+			MaxAge:      env.duration("FIBONACCI_CORS_MAX_AGE", 10*time.Minute),
+		},
+		SecurityHeaders: env.bool("FIBONACCI_SECURITY_HEADERS", true),
+		WebSocket: gateway.WebSocketOptions{
+			RequestTimeout: env.duration("FIBONACCI_WEBSOCKET_REQUEST_TIMEOUT", 10*time.Second),
+			PingInterval:   env.duration("FIBONACCI_WEBSOCKET_PING_INTERVAL", 30*time.Second),
+			WriteTimeout:   env.duration("FIBONACCI_WEBSOCKET_WRITE_TIMEOUT", 10*time.Second),
+		},
+		EventStream: gateway.EventStreamOptions{
+			Interval: env.duration("FIBONACCI_EVENT_STREAM_INTERVAL", 0),
+		},
+		MCPStdio:       env.bool("FIBONACCI_MCP_STDIO", false),
+		IdempotencyTTL: env.duration("FIBONACCI_IDEMPOTENCY_TTL", 10*time.Minute),
+	}
+	cfg.WebSocket.Origins = cfg.CORS.Origins
+
+	if cfg.Admission.MaxInFlight < 0 || cfg.Admission.MaxQueue < 0 || cfg.Admission.MaxGoroutines < 0 {
+		env.errs = append(env.errs, errors.New("FIBONACCI_MAX_IN_FLIGHT, FIBONACCI_MAX_QUEUE, FIBONACCI_MAX_GOROUTINES: must not be negative"))
+	}
+
+	if cfg.CompressionThreshold < 0 {
+		env.errs = append(env.errs, errors.New("FIBONACCI_COMPRESSION_THRESHOLD: must not be negative"))
+	}
+
+	if cfg.HTTP.ReadHeaderTimeout < 0 || cfg.HTTP.ReadTimeout < 0 || cfg.HTTP.WriteTimeout < 0 || cfg.HTTP.IdleTimeout < 0 {
+		env.errs = append(env.errs, errors.New("FIBONACCI_HTTP_READ_HEADER_TIMEOUT, FIBONACCI_HTTP_READ_TIMEOUT, FIBONACCI_HTTP_WRITE_TIMEOUT, FIBONACCI_HTTP_IDLE_TIMEOUT: must not be negative"))
+	}
+
+	if cfg.HTTP.MaxHeaderBytes < 0 || cfg.HTTP.MaxBodyBytes < 0 || cfg.HTTP.MaxConns < 0 || cfg.HTTP.MaxConnsPerClient < 0 {
+		env.errs = append(env.errs, errors.New("FIBONACCI_HTTP_MAX_HEADER_BYTES, FIBONACCI_HTTP_MAX_BODY_BYTES, FIBONACCI_HTTP_MAX_CONNS, FIBONACCI_HTTP_MAX_CONNS_PER_CLIENT: must not be negative"))
+	}
+
+	if err := cfg.CORS.Validate(); err != nil {
+		env.errs = append(env.errs, fmt.Errorf("FIBONACCI_CORS_CREDENTIALS: %w", err))
+	}
+
+	if cfg.AccessLogSampleRate < 0 || cfg.AccessLogSampleRate > 1 {
+		env.errs = append(env.errs, errors.New("FIBONACCI_ACCESS_LOG_SAMPLE_RATE: must be between 0 and 1"))
+	}
+
+	if cfg.AuthJWKS != "" && (cfg.AuthIssuer == "" || cfg.AuthAudience == "") {
+		env.errs = append(env.errs, errors.New("FIBONACCI_AUTH_JWKS: issuer and audience are required"))
+	}
+
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// env collects parsing errors, so that all invalid variables are reported at once.
+type env struct {
+	errs []error
+}
+
+func (e *env) bool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+		return fallback
+	}
+
+	return parsed
+}
+
+func (e *env) duration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+		return fallback
+	}
+
+	return parsed
+}
+
+func (e *env) int(key string, fallback int64) int64 {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+		return fallback
+	}
+
+	return parsed
+}
+
+func (e *env) float(key string, fallback float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+		return fallback
+	}
+
+	return parsed
+}
+
+func (e *env) list(key string) []string {
+	var items []string
+	for item := range strings.SplitSeq(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func (e *env) rateLimits(key string) map[string]ratelimit.Limit {
+	limits, err := ratelimit.ParseLimits(os.Getenv(key))
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+		return nil
+	}
+
+	return limits
+}
+
+func (e *env) weights(key string) map[string]float64 {
+	weights, err := admission.ParseWeights(os.Getenv(key))
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+		return nil
+	}
+
+	return weights
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/domust/fibonacci/internal/admission"
+	"github.com/domust/fibonacci/internal/ratelimit"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.False(t, cfg.Reflection)
+		require.Empty(t, cfg.RateLimits)
+		require.True(t, cfg.AccessLog)
+		require.Equal(t, int64(4096), cfg.Admission.MaxInFlight)
+		require.Equal(t, time.Second, cfg.Admission.QueueTimeout)
+		require.Empty(t, cfg.Admission.Weights)
+		require.Equal(t, 10*time.Nanosecond, cfg.CostPerWord)
+		require.Equal(t, time.Second, cfg.MaxBudget)
+		require.Equal(t, int64(1024), cfg.CompressionThreshold)
+		require.Equal(t, 365*24*time.Hour, cfg.CacheMaxAge)
+		require.Equal(t, 5*time.Second, cfg.HTTP.ReadHeaderTimeout)
+		require.Equal(t, 2*time.Minute, cfg.HTTP.IdleTimeout)
+		require.Equal(t, 16<<10, cfg.HTTP.MaxHeaderBytes)
+		require.Equal(t, int64(1<<20), cfg.HTTP.MaxBodyBytes)
+		require.Equal(t, 10000, cfg.HTTP.MaxConns)
+		require.Zero(t, cfg.HTTP.MaxConnsPerClient)
+		require.Empty(t, cfg.CORS.Origins)
+		require.Equal(t, 10*time.Minute, cfg.CORS.MaxAge)
+		require.True(t, cfg.SecurityHeaders)
+		require.Equal(t, 30*time.Second, cfg.WebSocket.PingInterval)
+		require.Zero(t, cfg.EventStream.Interval)
+		require.False(t, cfg.MCPStdio)
+		require.Equal(t, 10*time.Minute, cfg.IdempotencyTTL)
+		require.Equal(t, 1.0, cfg.AccessLogSampleRate)
+		require.Empty(t, cfg.AccessLogRedact)
+		require.Equal(t, 5*time.Second, cfg.ShutdownDrain)
+		require.Equal(t, 10*time.Second, cfg.ShutdownTimeout)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		t.Setenv("FIBONACCI_REFLECTION", "true")
+		t.Setenv("FIBONACCI_SHUTDOWN_DRAIN", "0s")
+		t.Setenv("FIBONACCI_RATE_LIMITS", "*=10:20")
+		t.Setenv("FIBONACCI_SHUTDOWN_TIMEOUT", "1m")
+		t.Setenv("FIBONACCI_ACCESS_LOG_SAMPLE_RATE", "0.1")
+		t.Setenv("FIBONACCI_ACCESS_LOG_REDACT", "peer, length")
+		t.Setenv("FIBONACCI_MAX_IN_FLIGHT", "0")
+		t.Setenv("FIBONACCI_TARGET_LATENCY", "50ms")
+		t.Setenv("FIBONACCI_ADMISSION_WEIGHTS", "*=0.5")
+		t.Setenv("FIBONACCI_MAX_BUDGET", "0s")
+		t.Setenv("FIBONACCI_CACHE_MAX_AGE", "0s")
+		t.Setenv("FIBONACCI_HTTP_WRITE_TIMEOUT", "0s")
+		t.Setenv("FIBONACCI_HTTP_MAX_CONNS_PER_CLIENT", "8")
+		t.Setenv("FIBONACCI_CORS_ORIGINS", "https://dashboard.example.com, https://*.example.org")
+		t.Setenv("FIBONACCI_CORS_CREDENTIALS", "true")
+		t.Setenv("FIBONACCI_SECURITY_HEADERS", "false")
+		t.Setenv("FIBONACCI_WEBSOCKET_PING_INTERVAL", "5s")
+		t.Setenv("FIBONACCI_EVENT_STREAM_INTERVAL", "1s")
+		t.Setenv("FIBONACCI_MCP_STDIO", "true")
+		t.Setenv("FIBONACCI_IDEMPOTENCY_TTL", "1h")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		require.True(t, cfg.Reflection)
+		require.Zero(t, cfg.ShutdownDrain)
+		require.Equal(t, map[string]ratelimit.Limit{ratelimit.Default: {Rate: 10, Burst: 20}}, cfg.RateLimits)
+		require.Equal(t, time.Minute, cfg.ShutdownTimeout)
+		require.Equal(t, 0.1, cfg.AccessLogSampleRate)
+		require.Equal(t, []string{"peer", "length"}, cfg.AccessLogRedact)
+		require.Zero(t, cfg.Admission.MaxInFlight)
+		require.Equal(t, 50*time.Millisecond, cfg.Admission.TargetLatency)
+		require.Equal(t, map[string]float64{admission.Default: 0.5}, cfg.Admission.Weights)
+		require.Zero(t, cfg.MaxBudget)
+		require.Zero(t, cfg.CacheMaxAge)
+		require.Zero(t, cfg.HTTP.WriteTimeout)
+		require.Equal(t, 8, cfg.HTTP.MaxConnsPerClient)
+		require.Equal(t, []string{"https://dashboard.example.com", "https://*.example.org"}, cfg.CORS.Origins)
+		require.True(t, cfg.CORS.Credentials)
+		require.False(t, cfg.SecurityHeaders)
+		require.Equal(t, 5*time.Second, cfg.WebSocket.PingInterval)
+		require.Equal(t, cfg.CORS.Origins, cfg.WebSocket.Origins)
+		require.Equal(t, time.Second, cfg.EventStream.Interval)
+		require.True(t, cfg.MCPStdio)
+		require.Equal(t, time.Hour, cfg.IdempotencyTTL)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		t.Setenv("FIBONACCI_REFLECTION", "maybe")
+		t.Setenv("FIBONACCI_SHUTDOWN_DRAIN", "soon")
+		t.Setenv("FIBONACCI_RATE_LIMITS", "*=10")
+		t.Setenv("FIBONACCI_AUTH_JWKS", "jwks.json")
+		t.Setenv("FIBONACCI_ACCESS_LOG_SAMPLE_RATE", "2")
+		t.Setenv("FIBONACCI_MAX_QUEUE", "many")
+		t.Setenv("FIBONACCI_ADMISSION_WEIGHTS", "*=0")
+		t.Setenv("FIBONACCI_COMPRESSION_THRESHOLD", "-1")
+		t.Setenv("FIBONACCI_HTTP_IDLE_TIMEOUT", "-1s")
+		t.Setenv("FIBONACCI_HTTP_MAX_BODY_BYTES", "-1")
+		t.Setenv("FIBONACCI_CORS_ORIGINS", "*")
+		t.Setenv("FIBONACCI_CORS_CREDENTIALS", "true")
+
+		cfg, err := Load()
+		require.ErrorContains(t, err, "FIBONACCI_REFLECTION")
+		require.ErrorContains(t, err, "FIBONACCI_SHUTDOWN_DRAIN")
+		require.ErrorContains(t, err, "FIBONACCI_RATE_LIMITS")
+		require.ErrorContains(t, err, "FIBONACCI_AUTH_JWKS")
+		require.ErrorContains(t, err, "FIBONACCI_ACCESS_LOG_SAMPLE_RATE")
+		require.ErrorContains(t, err, "FIBONACCI_MAX_QUEUE")
+		require.ErrorContains(t, err, "FIBONACCI_ADMISSION_WEIGHTS")
+		require.ErrorContains(t, err, "FIBONACCI_COMPRESSION_THRESHOLD")
+		require.ErrorContains(t, err, "FIBONACCI_HTTP_IDLE_TIMEOUT")
+		require.ErrorContains(t, err, "FIBONACCI_HTTP_MAX_BODY_BYTES")
+		require.ErrorContains(t, err, "FIBONACCI_CORS_CREDENTIALS")
+		require.Nil(t, cfg)
+	})
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"buf.build/go/protovalidate"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/domust/fibonacci/api"
+	apiv2 "github.com/domust/fibonacci/api/v2"
+	rpc "github.com/domust/fibonacci/internal/grpc"
+)
+
+// reference returns the sequence of the given length the way v1 computed it before being adapted over v2.
+func reference(length uint32) []uint64 {
+	seq := make([]uint64, 0, length)
+	var a, b uint64 = 0, 1
+	for range length {
+		seq = append(seq, a)
+		a, b = b, a+b
+	}
+
+	return seq
+}
+
+// TestConformance proves that v1 served as an adapter over v2 behaves the same as before, side by side with v2.
+func TestConformance(t *testing.T) {
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+
+	s := rpc.NewServer(nil, validator)
+	api.RegisterFibonacciServer(s, NewServer(nil))
+	apiv2.RegisterFibonacciServer(s, NewServerV2(nil))
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough://", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	v1 := api.NewFibonacciClient(conn)
+	v2 := apiv2.NewFibonacciClient(conn)
+	ctx := context.Background()
+
+	t.Run("sequences", func(t *testing.T) {
+		for length := uint32(1); length <= 94; length++ {
+			resp, err := v1.GenerateSequence(ctx, &api.GenerateSequenceRequest{Length: length})
+			require.NoError(t, err)
+			require.Equal(t, reference(length), resp.GetSequence(), "length %d", length)
+		}
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		seq := reference(94)
+		for index := range uint32(94) {
+			resp, err := v1.GetNumber(ctx, &api.GetNumberRequest{Index: index})
+			require.NoError(t, err)
+			require.Equal(t, seq[index], resp.GetNumber(), "index %d", index)
+		}
+	})
+
+	t.Run("streams", func(t *testing.T) {
+		for _, length := range []uint32{1, 2, 10, 94} {
+			stream, err := v1.StreamSequence(ctx, &api.GenerateSequenceRequest{Length: length})
+			require.NoError(t, err)
+
+			var seq []uint64
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				require.Equal(t, uint32(len(seq)), resp.GetIndex())
+				seq = append(seq, resp.GetValue())
+			}
+			require.Equal(t, reference(length), seq, "length %d", length)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		_, err := v1.GenerateSequence(ctx, &api.GenerateSequenceRequest{})
+		require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+
+		_, err = v1.GenerateSequence(ctx, &api.GenerateSequenceRequest{Length: 95})
+		require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+
+		_, err = v1.GetNumber(ctx, &api.GetNumberRequest{Index: 94})
+		require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+
+		stream, err := v1.StreamSequence(ctx, &api.GenerateSequenceRequest{Length: 95})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+	})
+
+	t.Run("agrees with v2", func(t *testing.T) {
+		seq, err := v1.GenerateSequence(ctx, &api.GenerateSequenceRequest{Length: 94})
+		require.NoError(t, err)
+
+		resp, err := v2.ListNumbers(ctx, &apiv2.ListNumbersRequest{PageSize: 95})
+		require.NoError(t, err)
+		require.Len(t, resp.GetNumbers(), 95)
+		for i, value := range seq.GetSequence() {
+			require.Equal(t, value, resp.GetNumbers()[i].GetUint64(), "index %d", i)
+		}
+		// v2 goes on where v1 has to stop
+		require.Equal(t, "19740274219868223167", resp.GetNumbers()[94].GetBig())
+	})
+}
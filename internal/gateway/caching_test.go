@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/domust/fibonacci/internal/auth"
+	"github.com/domust/fibonacci/internal/caching"
+	rpc "github.com/domust/fibonacci/internal/grpc"
+)
+
+func TestCaching(t *testing.T) {
+	mux := caching.Middleware(Format(proxy(t, rpc.WithCaching(caching.New(time.Hour)))))
+
+	get := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/v1/generate?length=5", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "public, max-age=3600, immutable", rec.Header().Get("Cache-Control"))
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// routes of the same request share the entity tag
+	require.Equal(t, etag, get("/api/v1/sequences/5", "").Header().Get("ETag"))
+	require.NotEqual(t, etag, get("/api/v1/sequences/6", "").Header().Get("ETag"))
+	require.NotEqual(t, etag, get("/api/v1/sequences/5?format=csv", "").Header().Get("ETag"))
+
+	// representations negotiated with the Accept header have distinct entity tags too
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/sequences/5", nil)
+	req.Header.Set("Accept", "text/csv")
+	csv := httptest.NewRecorder()
+	mux.ServeHTTP(csv, req)
+	require.Equal(t, http.StatusOK, csv.Code)
+	require.NotEqual(t, etag, csv.Header().Get("ETag"))
+	require.Contains(t, rec.Header().Values("Vary"), "Accept")
+	require.Contains(t, csv.Header().Values("Vary"), "Accept")
+
+	rec = get("/api/v1/sequences/5", etag)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.String())
+	require.Equal(t, etag, rec.Header().Get("ETag"))
+
+	// errors are never cached
+	rec = get("/api/v1/sequences/95", "")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Empty(t, rec.Header().Get("ETag"))
+	require.Empty(t, rec.Header().Get("Cache-Control"))
+}
+
+func TestCachingAuthenticated(t *testing.T) {
+	keys := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(keys, []byte(`[{"name": "dashboard", "key": "secret"}]`), 0o600))
+	authenticator, err := auth.New(auth.WithAPIKeys(keys))
+	require.NoError(t, err)
+	mux := caching.Middleware(Format(proxy(t, rpc.WithCaching(caching.New(time.Hour)), rpc.WithAuth(authenticator))))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/sequences/5", nil)
+	req.Header.Set("X-Api-Key", "secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("ETag"))
+	require.Equal(t, "private, max-age=3600, immutable", rec.Header().Get("Cache-Control"))
+	require.Subset(t, rec.Header().Values("Vary"), []string{"Accept", "Authorization", "X-Api-Key"})
+}
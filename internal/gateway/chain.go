@@ -0,0 +1,21 @@
+package gateway
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Middleware wraps a handler with additional behavior.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps the handler with middlewares, the first of which is the outermost one.
+// Nil middlewares are skipped, so that optional ones can be passed as is.
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for _, m := range slices.Backward(middlewares) {
+		if m != nil {
+			handler = m(handler)
+		}
+	}
+
+	return handler
+}
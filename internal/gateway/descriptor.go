@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/domust/fibonacci/api"
+	apiv2 "github.com/domust/fibonacci/api/v2"
+)
+
+// RegisterDescriptors serves the FileDescriptorSet of both versions of the API at /descriptors, so that tools such as
+// `buf curl --schema` can talk to the service without a checkout of the repository.
+// The set is encoded in binary unless JSON is requested via the Accept header.
+func RegisterDescriptors(mux *runtime.ServeMux) error {
+	set := descriptors(api.File_api_v1_api_proto, apiv2.File_api_v2_api_proto)
+
+	binpb, err := proto.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("descriptors: %w", err)
+	}
+
+	jsonpb, err := protojson.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("descriptors: %w", err)
+	}
+
+	err = mux.HandlePath(http.MethodGet, "/descriptors", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			serve("application/json", jsonpb)(w, r, nil)
+			return
+		}
+		serve("application/x-protobuf", binpb)(w, r, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("descriptors: %w", err)
+	}
+
+	return nil
+}
+
+// descriptors returns a self-contained set of the given files and their transitive imports in topological order.
+func descriptors(files ...protoreflect.FileDescriptor) *descriptorpb.FileDescriptorSet {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+
+	var visit func(protoreflect.FileDescriptor)
+	visit = func(file protoreflect.FileDescriptor) {
+		if seen[file.Path()] {
+			return
+		}
+		seen[file.Path()] = true
+
+		imports := file.Imports()
+		for i := range imports.Len() {
+			visit(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+
+	for _, file := range files {
+		visit(file)
+	}
+
+	return set
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/domust/fibonacci/api"
+	apiv2 "github.com/domust/fibonacci/api/v2"
+)
+
+func TestRegisterDescriptors(t *testing.T) {
+	mux := runtime.NewServeMux()
+	require.NoError(t, RegisterDescriptors(mux))
+
+	tests := map[string]struct {
+		accept      string
+		contentType string
+		unmarshal   func([]byte, proto.Message) error
+	}{
+		"binary": {
+			contentType: "application/x-protobuf",
+			unmarshal:   proto.Unmarshal,
+		},
+		"json": {
+			accept:      "application/json",
+			contentType: "application/json",
+			unmarshal:   protojson.Unmarshal,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/descriptors", nil)
+			req.Header.Set("Accept", data.accept)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, data.contentType, rec.Header().Get("Content-Type"))
+
+			var set descriptorpb.FileDescriptorSet
+			require.NoError(t, data.unmarshal(rec.Body.Bytes(), &set))
+
+			// the set must be self-contained in order to be usable by generic tooling
+			files, err := protodesc.NewFiles(&set)
+			require.NoError(t, err)
+
+			for _, file := range []protoreflect.FileDescriptor{api.File_api_v1_api_proto, apiv2.File_api_v2_api_proto} {
+				service, err := files.FindDescriptorByName(file.Services().Get(0).FullName())
+				require.NoError(t, err)
+				require.NotNil(t, service)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/api"
+)
+
+// EventStreamPath is the path of the endpoint streaming sequences as server-sent events.
+const EventStreamPath = "/api/v1/generate/stream"
+
+// Types of events sent besides the default message events carrying terms.
+const (
+	EndEvent   = "end"
+	ErrorEvent = "error"
+)
+
+// EventStreamOptions tune the pace of server-sent events.
+type EventStreamOptions struct {
+	// Interval is the pause between consecutive terms, terms are sent as soon as they are generated when zero.
+	Interval time.Duration
+}
+
+// RegisterEventStream streams sequences of the given length as server-sent events, e.g. /api/v1/generate/stream?length=10.
+// Every term is a message event whose ID is its index and whose data is the same JSON object as sent over WebSockets.
+// Clients reconnecting with the Last-Event-ID header resume after the given index, the stream is finished with an end
+// event and failures after the first term are reported as error events carrying problem details.
+func RegisterEventStream(mux *runtime.ServeMux, client api.FibonacciClient, opts EventStreamOptions) error {
+	err := mux.HandlePath(http.MethodGet, EventStreamPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+
+		req, last, err := parseEventStreamRequest(r)
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+			return
+		}
+
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, api.Fibonacci_StreamSequence_FullMethodName, runtime.WithHTTPPathPattern(EventStreamPath))
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+			return
+		}
+
+		stream, err := client.StreamSequence(ctx, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		// the write deadline of the HTTP server is meant for requests, not for paced streams
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		var ticker *time.Ticker
+		if opts.Interval > 0 {
+			ticker = time.NewTicker(opts.Interval)
+			defer ticker.Stop()
+		}
+
+		var started bool
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				if !started {
+					// resuming after the last term, which stops browsers from reconnecting once again
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				_ = writeEvent(w, rc, EndEvent, "", []byte("{}"))
+				return
+			}
+			if err != nil {
+				if !started {
+					runtime.HTTPError(ctx, mux, outbound, w, r, err)
+					return
+				}
+				_ = writeEvent(w, rc, ErrorEvent, "", problemData(err, r))
+				return
+			}
+
+			if last >= 0 && int64(resp.GetIndex()) <= last {
+				continue
+			}
+
+			if !started {
+				started = true
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Cache-Control", "no-store")
+				w.Header().Set("X-Accel-Buffering", "no") // disables buffering by nginx
+				w.WriteHeader(http.StatusOK)
+			} else if ticker != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+
+			data, err := defaultMarshaler.Marshal(resp)
+			if err != nil {
+				_ = writeEvent(w, rc, ErrorEvent, "", problemData(err, r))
+				return
+			}
+			if err := writeEvent(w, rc, "", strconv.FormatUint(uint64(resp.GetIndex()), 10), data); err != nil {
+				return // the client has left
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("event stream %s: %w", EventStreamPath, err)
+	}
+
+	return nil
+}
+
+// parseEventStreamRequest returns the request along with the index of the last term received by the client,
+// which is negative for new streams.
+func parseEventStreamRequest(r *http.Request) (*api.GenerateSequenceRequest, int64, error) {
+	length, err := strconv.ParseUint(r.URL.Query().Get("length"), 10, 32)
+	if err != nil {
+		return nil, 0, status.Errorf(codes.InvalidArgument, "invalid length: %v", err)
+	}
+
+	last := int64(-1)
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		index, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return nil, 0, status.Errorf(codes.InvalidArgument, "invalid Last-Event-ID: %v", err)
+		}
+		last = int64(index)
+	}
+
+	return &api.GenerateSequenceRequest{Length: uint32(length)}, last, nil
+}
+
+// writeEvent writes a single event and flushes it to the client right away.
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event, id string, data []byte) error {
+	if event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+
+	return rc.Flush()
+}
+
+// problemData returns the problem details of the error, as sent in error events.
+func problemData(err error, r *http.Request) []byte {
+	data, _ := json.Marshal(NewProblem(status.Convert(err), r.URL.Path))
+	return data
+}
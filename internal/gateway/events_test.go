@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/domust/fibonacci/api"
+)
+
+// event is a single server-sent event.
+type event struct {
+	typ  string
+	id   string
+	data string
+}
+
+// parseEvents splits the body of an event stream into events.
+func parseEvents(t *testing.T, body string) []event {
+	t.Helper()
+
+	var events []event
+	for block := range strings.SplitSeq(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		var e event
+		for line := range strings.SplitSeq(block, "\n") {
+			field, value, ok := strings.Cut(line, ": ")
+			require.True(t, ok, "malformed line %q", line)
+			switch field {
+			case "event":
+				e.typ = value
+			case "id":
+				e.id = value
+			case "data":
+				e.data = value
+			}
+		}
+		events = append(events, e)
+	}
+
+	return events
+}
+
+func TestEventStream(t *testing.T) {
+	mux := NewServeMux()
+	require.NoError(t, RegisterEventStream(mux, api.NewFibonacciClient(dial(t)), EventStreamOptions{}))
+
+	seq := []uint64{0, 1, 1, 2, 3}
+
+	tests := map[string]struct {
+		target      string
+		lastEventID string
+		code        int
+		first       int
+	}{
+		"stream": {
+			target: "/api/v1/generate/stream?length=5",
+			code:   http.StatusOK,
+		},
+		"resumed": {
+			target:      "/api/v1/generate/stream?length=5",
+			lastEventID: "2",
+			code:        http.StatusOK,
+			first:       3,
+		},
+		"resumed after the end": {
+			target:      "/api/v1/generate/stream?length=5",
+			lastEventID: "4",
+			code:        http.StatusNoContent,
+		},
+		"invalid length": {
+			target: "/api/v1/generate/stream?length=95",
+			code:   http.StatusBadRequest,
+		},
+		"missing length": {
+			target: "/api/v1/generate/stream",
+			code:   http.StatusBadRequest,
+		},
+		"invalid last event id": {
+			target:      "/api/v1/generate/stream?length=5",
+			lastEventID: "first",
+			code:        http.StatusBadRequest,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, data.target, nil)
+			req.Header.Set("Accept", "text/event-stream")
+			if data.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", data.lastEventID)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			require.Equal(t, data.code, rec.Code)
+			switch data.code {
+			case http.StatusNoContent:
+				require.Empty(t, rec.Body.String())
+				return
+			case http.StatusBadRequest:
+				require.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+				return
+			}
+
+			require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+			events := parseEvents(t, rec.Body.String())
+			require.Len(t, events, len(seq)-data.first+1)
+			for i, e := range events[:len(events)-1] {
+				index := data.first + i
+				require.Empty(t, e.typ)
+				require.Equal(t, fmt.Sprint(index), e.id)
+				require.JSONEq(t, fmt.Sprintf(`{"index":%d,"value":"%d"}`, index, seq[index]), e.data)
+			}
+			require.Equal(t, event{typ: EndEvent, data: "{}"}, events[len(events)-1])
+		})
+	}
+}
+
+func TestEventStreamPacing(t *testing.T) {
+	const interval = 100 * time.Millisecond
+
+	mux := NewServeMux()
+	require.NoError(t, RegisterEventStream(mux, api.NewFibonacciClient(dial(t)), EventStreamOptions{Interval: interval}))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL + "/api/v1/generate/stream?length=4")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// the first term is sent right away, so that clients can tell the stream has started
+	var e struct {
+		Index int `json:"index"`
+	}
+	buf := make([]byte, 64)
+	n, err := resp.Body.Read(buf)
+	require.NoError(t, err)
+	require.Less(t, time.Since(start), interval)
+	_, data, _ := strings.Cut(strings.TrimSpace(string(buf[:n])), "data: ")
+	require.NoError(t, json.Unmarshal([]byte(data), &e))
+	require.Zero(t, e.Index)
+
+	for {
+		if _, err := resp.Body.Read(buf); err != nil {
+			break
+		}
+	}
+	require.GreaterOrEqual(t, time.Since(start), 3*interval)
+}
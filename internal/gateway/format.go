@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Media types of alternative representations of sequences.
+const (
+	CSV      = "text/csv"
+	NDJSON   = "application/x-ndjson"
+	Text     = "text/plain"
+	Protobuf = "application/x-protobuf"
+)
+
+// FormatParameter is the query parameter selecting the representation of the response, taking precedence over Accept.
+const FormatParameter = "format"
+
+// formats maps values of the format parameter to media types.
+var formats = map[string]string{
+	"json":     "application/json",
+	"csv":      CSV,
+	"ndjson":   NDJSON,
+	"text":     Text,
+	"protobuf": Protobuf,
+}
+
+// defaultMarshaler is the JSON marshaler used by [runtime.ServeMux] by default.
+var defaultMarshaler = &runtime.HTTPBodyMarshaler{
+	Marshaler: &runtime.JSONPb{
+		MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+		UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+	},
+}
+
+// marshalers returns options registering marshalers of alternative representations.
+func marshalers() []runtime.ServeMuxOption {
+	return []runtime.ServeMuxOption{
+		runtime.WithMarshalerOption(CSV, &sequenceMarshaler{contentType: CSV, encode: encodeCSV}),
+		runtime.WithMarshalerOption(NDJSON, &sequenceMarshaler{contentType: NDJSON, encode: encodeNDJSON}),
+		runtime.WithMarshalerOption(Text, &sequenceMarshaler{contentType: Text, encode: encodeText}),
+		runtime.WithMarshalerOption(Protobuf, &protoMarshaler{}),
+	}
+}
+
+// Format selects the representation of the response by the format query parameter, by setting the Accept header.
+func Format(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get(FormatParameter)
+		if format == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mime, ok := formats[format]
+		if !ok {
+			problem := NewProblem(status.Newf(codes.InvalidArgument, "unsupported format %q", format), r.URL.Path)
+			w.Header().Set("Content-Type", ProblemContentType)
+			w.WriteHeader(problem.Status)
+			_ = json.NewEncoder(w).Encode(problem)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept", mime)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// protoMarshaler is the binary protobuf marshaler with a content type naming the format.
+type protoMarshaler struct {
+	runtime.ProtoMarshaller
+}
+
+func (*protoMarshaler) ContentType(any) string {
+	return Protobuf
+}
+
+// sequence is implemented by responses carrying a sequence.
+type sequence interface {
+	GetSequence() []uint64
+}
+
+// sequenceMarshaler represents sequences in the given format, while other messages are represented as JSON.
+type sequenceMarshaler struct {
+	contentType string
+	encode      func(io.Writer, []uint64) error
+}
+
+func (m *sequenceMarshaler) ContentType(v any) string {
+	if _, ok := v.(sequence); ok {
+		return m.contentType
+	}
+
+	return defaultMarshaler.ContentType(v)
+}
+
+func (m *sequenceMarshaler) Marshal(v any) ([]byte, error) {
+	seq, ok := v.(sequence)
+	if !ok {
+		return defaultMarshaler.Marshal(v)
+	}
+
+	var buf bytes.Buffer
+	if err := m.encode(&buf, seq.GetSequence()); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m *sequenceMarshaler) Unmarshal(data []byte, v any) error {
+	return defaultMarshaler.Unmarshal(data, v)
+}
+
+func (m *sequenceMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return defaultMarshaler.NewDecoder(r)
+}
+
+func (m *sequenceMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v any) error {
+		if seq, ok := v.(sequence); ok {
+			return m.encode(w, seq.GetSequence())
+		}
+
+		return defaultMarshaler.NewEncoder(w).Encode(v)
+	})
+}
+
+// encodeCSV writes a header followed by a row of index and value per term.
+func encodeCSV(w io.Writer, seq []uint64) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"index", "value"}); err != nil {
+		return err
+	}
+	for i, num := range seq {
+		if err := cw.Write([]string{strconv.Itoa(i), strconv.FormatUint(num, 10)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// encodeNDJSON writes a JSON object with index and value per term and line.
+func encodeNDJSON(w io.Writer, seq []uint64) error {
+	for i, num := range seq {
+		if _, err := fmt.Fprintf(w, "{\"index\":%d,\"value\":%d}\n", i, num); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeText writes a term per line.
+func encodeText(w io.Writer, seq []uint64) error {
+	for _, num := range seq {
+		if _, err := fmt.Fprintln(w, num); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/domust/fibonacci/api"
+)
+
+func TestFormat(t *testing.T) {
+	mux := Format(proxy(t))
+
+	tests := map[string]struct {
+		target      string
+		accept      string
+		contentType string
+		body        string
+	}{
+		"json by default": {
+			target:      "/api/v1/generate?length=5",
+			contentType: "application/json",
+			body:        `{"sequence":["0","1","1","2","3"]}`,
+		},
+		"csv": {
+			target:      "/api/v1/generate?length=5",
+			accept:      CSV,
+			contentType: CSV,
+			body:        "index,value\n0,0\n1,1\n2,1\n3,2\n4,3\n",
+		},
+		"ndjson": {
+			target:      "/api/v1/generate?length=3",
+			accept:      NDJSON,
+			contentType: NDJSON,
+			body:        "{\"index\":0,\"value\":0}\n{\"index\":1,\"value\":1}\n{\"index\":2,\"value\":1}\n",
+		},
+		"text": {
+			target:      "/api/v1/generate?length=5",
+			accept:      Text,
+			contentType: Text,
+			body:        "0\n1\n1\n2\n3\n",
+		},
+		"format parameter": {
+			target:      "/api/v1/generate?length=5&format=text",
+			accept:      CSV,
+			contentType: Text,
+			body:        "0\n1\n1\n2\n3\n",
+		},
+		"json parameter": {
+			target:      "/api/v1/generate?length=1&format=json",
+			accept:      CSV,
+			contentType: "application/json",
+			body:        `{"sequence":["0"]}`,
+		},
+		"errors are problems": {
+			target:      "/api/v1/generate?length=0",
+			accept:      CSV,
+			contentType: ProblemContentType,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, data.target, nil)
+			if data.accept != "" {
+				req.Header.Set("Accept", data.accept)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			require.Equal(t, data.contentType, rec.Header().Get("Content-Type"))
+			if data.body != "" {
+				require.Equal(t, http.StatusOK, rec.Code)
+				if data.contentType == "application/json" {
+					require.JSONEq(t, data.body, rec.Body.String())
+				} else {
+					require.Equal(t, data.body, rec.Body.String())
+				}
+			}
+		})
+	}
+
+	t.Run("protobuf", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=94&format=protobuf", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, Protobuf, rec.Header().Get("Content-Type"))
+
+		var resp api.GenerateSequenceResponse
+		require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Len(t, resp.GetSequence(), 94)
+		require.Equal(t, uint64(12200160415121876738), resp.GetSequence()[93])
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=5&format=xml", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		require.Equal(t, `unsupported format "xml"`, problem.Detail)
+		require.Equal(t, "INVALID_ARGUMENT", problem.Code)
+	})
+}
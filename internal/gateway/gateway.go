@@ -0,0 +1,72 @@
+// Package gateway implements HTTP endpoints served alongside the REST API proxied to the grpc server.
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/textproto"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/peer"
+
+	"github.com/domust/fibonacci/internal/auth"
+	"github.com/domust/fibonacci/internal/caching"
+	"github.com/domust/fibonacci/internal/idempotency"
+	"github.com/domust/fibonacci/internal/ratelimit"
+)
+
+// NewServeMux returns the REST API multiplexer, which translates HTTP headers understood by the grpc server,
+// renders errors as problem details and represents sequences in formats selected by the Accept header.
+func NewServeMux(opts ...runtime.ServeMuxOption) *runtime.ServeMux {
+	defaults := append([]runtime.ServeMuxOption{
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+		runtime.WithErrorHandler(problemHandler),
+	}, marshalers()...)
+
+	return runtime.NewServeMux(append(defaults, opts...)...)
+}
+
+// IncomingContext returns the context of a call served in-process rather than proxied to the grpc server,
+// which carries request headers as incoming metadata and the address of the HTTP client as the peer,
+// so that interceptors authenticate and rate limit such calls the same way as proxied ones.
+func IncomingContext(mux *runtime.ServeMux, r *http.Request, fullMethod, pattern string) (context.Context, error) {
+	ctx, err := runtime.AnnotateIncomingContext(r.Context(), mux, r, fullMethod, runtime.WithHTTPPathPattern(pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	if addr, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: net.TCPAddrFromAddrPort(addr)})
+	}
+
+	return ctx, nil
+}
+
+func incomingHeader(key string) (string, bool) {
+	switch textproto.CanonicalMIMEHeaderKey(key) {
+	case textproto.CanonicalMIMEHeaderKey(auth.APIKeyHeader):
+		return auth.APIKeyHeader, true
+	case textproto.CanonicalMIMEHeaderKey(idempotency.KeyHeader):
+		return idempotency.KeyHeader, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+func outgoingHeader(key string) (string, bool) {
+	switch key {
+	case ratelimit.RetryAfterHeader:
+		return "Retry-After", true
+	case caching.ETagHeader:
+		return "ETag", true
+	case caching.CacheControlHeader:
+		return "Cache-Control", true
+	case idempotency.ReplayedHeader:
+		return "Idempotent-Replayed", true
+	}
+
+	return runtime.MetadataHeaderPrefix + key, true
+}
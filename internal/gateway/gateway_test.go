@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"buf.build/go/protovalidate"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal"
+	"github.com/domust/fibonacci/internal/admission"
+	"github.com/domust/fibonacci/internal/auth"
+	rpc "github.com/domust/fibonacci/internal/grpc"
+	"github.com/domust/fibonacci/internal/idempotency"
+	"github.com/domust/fibonacci/internal/ratelimit"
+)
+
+// proxy returns the REST API served by the gateway in front of a grpc server configured with the given options.
+func proxy(t *testing.T, opts ...rpc.Option) http.Handler {
+	t.Helper()
+
+	mux := NewServeMux()
+	require.NoError(t, api.RegisterFibonacciHandler(context.Background(), mux, dial(t, opts...)))
+
+	return mux
+}
+
+// dial returns the connection of the gateway to a grpc server configured with the given options.
+func dial(t *testing.T, opts ...rpc.Option) *grpc.ClientConn {
+	t.Helper()
+
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+
+	s := rpc.NewServer(nil, validator, opts...)
+	api.RegisterFibonacciServer(s, internal.NewServer(nil))
+
+	// the gateway dials the grpc server in-process, the same way as in production
+	pipe := rpc.NewPipe()
+	go func() {
+		_ = s.Serve(pipe)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///gateway", pipe.DialOption(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestRateLimit(t *testing.T) {
+	limits := map[string]ratelimit.Limit{
+		api.Fibonacci_GenerateSequence_FullMethodName: {Rate: 1.0 / 60, Burst: 1},
+	}
+
+	get := func(mux http.Handler, remote, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=3", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Api-Key", key)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		mux := proxy(t, rpc.WithRateLimit(ratelimit.New(limits)))
+
+		require.Equal(t, http.StatusOK, get(mux, "10.0.0.1:1234", "").Code)
+
+		rec := get(mux, "10.0.0.1:1234", "")
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		retry, err := time.ParseDuration(rec.Header().Get("Retry-After") + "s")
+		require.NoError(t, err)
+		require.InDelta(t, time.Minute.Seconds(), retry.Seconds(), 1)
+
+		// clients are told apart by their address, since made up API keys are not verified
+		require.Equal(t, http.StatusOK, get(mux, "10.0.0.2:1234", "").Code)
+		require.Equal(t, http.StatusTooManyRequests, get(mux, "10.0.0.1:1234", "made-up").Code)
+	})
+
+	t.Run("authenticated", func(t *testing.T) {
+		keys := filepath.Join(t.TempDir(), "keys.json")
+		require.NoError(t, os.WriteFile(keys, []byte(`[{"name": "dashboard", "key": "secret"}, {"name": "cli", "key": "other"}]`), 0o600))
+		authenticator, err := auth.New(auth.WithAPIKeys(keys))
+		require.NoError(t, err)
+		mux := proxy(t, rpc.WithAuth(authenticator), rpc.WithRateLimit(ratelimit.New(limits)))
+
+		// principals are told apart regardless of their address
+		require.Equal(t, http.StatusOK, get(mux, "10.0.0.1:1234", "secret").Code)
+		require.Equal(t, http.StatusTooManyRequests, get(mux, "10.0.0.2:1234", "secret").Code)
+		require.Equal(t, http.StatusOK, get(mux, "10.0.0.1:1234", "other").Code)
+	})
+}
+
+func TestIdempotency(t *testing.T) {
+	mux := proxy(t, rpc.WithIdempotency(idempotency.New(time.Hour, nil)))
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/sequences:generate", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("a", `{"length": 3}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+
+	rec = post("a", `{"length": 3}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+	require.JSONEq(t, `{"sequence": ["0", "1", "1"]}`, rec.Body.String())
+
+	require.Equal(t, http.StatusBadRequest, post("a", `{"length": 4}`).Code)
+	require.Equal(t, http.StatusOK, post("b", `{"length": 4}`).Code)
+}
+
+func TestAdmission(t *testing.T) {
+	controller := admission.New(admission.Limits{MaxInFlight: 10, QueueTimeout: 2 * time.Second}, nil)
+	mux := proxy(t, rpc.WithAdmission(controller))
+
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=5", nil))
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, get().Code)
+
+	// capacity left is too small for the request, which is shed since queueing is disabled
+	release, err := controller.Acquire(context.Background(), api.Fibonacci_GenerateSequence_FullMethodName, 6)
+	require.NoError(t, err)
+	rec := get()
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	// invalid lengths are rejected before their cost is estimated, rather than being charged the whole capacity
+	huge := httptest.NewRecorder()
+	mux.ServeHTTP(huge, httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=4294967295", nil))
+	require.Equal(t, http.StatusBadRequest, huge.Code)
+	require.Zero(t, controller.Queue())
+
+	release()
+	require.Equal(t, http.StatusOK, get().Code)
+}
+
+func TestAuth(t *testing.T) {
+	keys := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(keys, []byte(`[{"name": "dashboard", "key": "secret"}]`), 0o600))
+
+	authenticator, err := auth.New(auth.WithAPIKeys(keys))
+	require.NoError(t, err)
+	mux := proxy(t, rpc.WithAuth(authenticator))
+
+	tests := map[string]struct {
+		header string
+		value  string
+		code   int
+		body   string
+	}{
+		"anonymous": {
+			code: http.StatusUnauthorized,
+			body: "missing credentials",
+		},
+		"api key": {
+			header: "X-Api-Key",
+			value:  "secret",
+			code:   http.StatusOK,
+		},
+		"invalid api key": {
+			header: "X-Api-Key",
+			value:  "guess",
+			code:   http.StatusUnauthorized,
+			body:   "invalid api key",
+		},
+		"authorization header": {
+			header: "Authorization",
+			value:  "Basic ZGFzaGJvYXJkOnNlY3JldA==",
+			code:   http.StatusUnauthorized,
+			body:   "unsupported authorization scheme",
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=3", nil)
+			if data.header != "" {
+				req.Header.Set(data.header, data.value)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			require.Equal(t, data.code, rec.Code)
+			require.Contains(t, rec.Body.String(), data.body)
+		})
+	}
+}
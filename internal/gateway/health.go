@@ -0,0 +1,41 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+
+	"github.com/domust/fibonacci/internal/health"
+)
+
+// RegisterHealth serves Kubernetes probes, which respond with 503 when the respective condition is not met:
+//   - /healthz is the startup probe, which succeeds once startup checks have passed;
+//   - /livez is the liveness probe, which fails only when the process is broken;
+//   - /readyz is the readiness probe, which fails while starting, draining or when dependencies are unhealthy.
+func RegisterHealth(mux *runtime.ServeMux, h *health.Health) error {
+	probes := map[string]func(health.Report) bool{
+		"/healthz": func(r health.Report) bool { return r.Started },
+		"/livez":   func(r health.Report) bool { return r.Live },
+		"/readyz":  func(r health.Report) bool { return r.Ready },
+	}
+
+	for path, probe := range probes {
+		err := mux.HandlePath(http.MethodGet, path, func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+			report := h.Report()
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			if !probe(report) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			_ = json.NewEncoder(w).Encode(report)
+		})
+		if err != nil {
+			return fmt.Errorf("health %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
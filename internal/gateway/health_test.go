@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	grpchealth "google.golang.org/grpc/health"
+
+	"github.com/domust/fibonacci/internal/health"
+)
+
+func TestRegisterHealth(t *testing.T) {
+	var broken bool
+	h := health.New(grpchealth.NewServer(), "api.v1.Fibonacci")
+	h.Add(health.Check{Name: "self-test", Kind: health.Liveness, Check: func(context.Context) error {
+		if broken {
+			return errors.New("broken")
+		}
+		return nil
+	}})
+
+	mux := runtime.NewServeMux()
+	require.NoError(t, RegisterHealth(mux, h))
+
+	probe := func(t *testing.T, path string) (int, health.Report) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		return rec.Code, report
+	}
+
+	// run evaluates checks once, since the run loop returns as soon as ctx is done.
+	run := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		h.Run(ctx, time.Hour, time.Second)
+	}
+
+	steps := []struct {
+		name    string
+		prepare func()
+		codes   map[string]int
+	}{
+		{
+			name:    "starting",
+			prepare: func() {},
+			codes:   map[string]int{"/healthz": http.StatusServiceUnavailable, "/livez": http.StatusOK, "/readyz": http.StatusServiceUnavailable},
+		},
+		{
+			name:    "serving",
+			prepare: run,
+			codes:   map[string]int{"/healthz": http.StatusOK, "/livez": http.StatusOK, "/readyz": http.StatusOK},
+		},
+		{
+			name: "broken",
+			prepare: func() {
+				broken = true
+				run()
+			},
+			codes: map[string]int{"/healthz": http.StatusOK, "/livez": http.StatusServiceUnavailable, "/readyz": http.StatusServiceUnavailable},
+		},
+		{
+			name: "draining",
+			prepare: func() {
+				broken = false
+				run()
+				h.Shutdown()
+			},
+			codes: map[string]int{"/healthz": http.StatusOK, "/livez": http.StatusOK, "/readyz": http.StatusServiceUnavailable},
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			step.prepare()
+			for path, code := range step.codes {
+				got, _ := probe(t, path)
+				require.Equal(t, code, got, path)
+			}
+		})
+	}
+
+	_, report := probe(t, "/healthz")
+	require.Equal(t, map[string]string{"self-test": "ok"}, report.Checks)
+}
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+
+	"github.com/domust/fibonacci/api"
+)
+
+//go:embed explorer.html
+var explorer []byte
+
+// RegisterOpenAPI serves the OpenAPI document at /openapi.json and the interactive API explorer at /docs.
+func RegisterOpenAPI(mux *runtime.ServeMux) error {
+	if err := mux.HandlePath(http.MethodGet, "/openapi.json", serve("application/json", api.OpenAPI)); err != nil {
+		return fmt.Errorf("openapi: %w", err)
+	}
+
+	if err := mux.HandlePath(http.MethodGet, "/docs", serve("text/html; charset=utf-8", explorer)); err != nil {
+		return fmt.Errorf("explorer: %w", err)
+	}
+
+	return nil
+}
+
+func serve(contentType string, body []byte) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(body)
+	}
+}
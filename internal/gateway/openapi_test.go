@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/domust/fibonacci/api"
+	apiv2 "github.com/domust/fibonacci/api/v2"
+)
+
+type openapi struct {
+	Paths map[string]map[string]struct {
+		OperationID string `json:"operationId"`
+	} `json:"paths"`
+	Definitions map[string]struct {
+		Properties map[string]any `json:"properties"`
+	} `json:"definitions"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	var spec openapi
+	require.NoError(t, json.Unmarshal(api.OpenAPI, &spec))
+
+	// both versions are documented by the same document
+	files := []protoreflect.FileDescriptor{api.File_api_v1_api_proto, apiv2.File_api_v2_api_proto}
+
+	// bindings collects every HTTP rule declared in the proto as "verb path" pairs.
+	bindings := make(map[string]protoreflect.MethodDescriptor)
+	for _, file := range files {
+		services := file.Services()
+		for i := range services.Len() {
+			methods := services.Get(i).Methods()
+			for j := range methods.Len() {
+				method := methods.Get(j)
+				rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+				if !ok || rule == nil {
+					continue
+				}
+				for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+					verb, path := binding(r)
+					bindings[verb+" "+path] = method
+				}
+			}
+		}
+	}
+	require.NotEmpty(t, bindings)
+
+	t.Run("every binding is documented", func(t *testing.T) {
+		for key, method := range bindings {
+			verb, path, _ := strings.Cut(key, " ")
+			op, ok := spec.Paths[path][verb]
+			require.True(t, ok, "%s is missing from the OpenAPI document", key)
+
+			prefix := string(method.Parent().Name()) + "_" + string(method.Name())
+			require.True(t, strings.HasPrefix(op.OperationID, prefix), "%s has unexpected operation id %q", key, op.OperationID)
+		}
+	})
+
+	t.Run("every documented path is bound", func(t *testing.T) {
+		for path, ops := range spec.Paths {
+			for verb := range ops {
+				require.Contains(t, bindings, verb+" "+path, "OpenAPI document contains stale operation")
+			}
+		}
+	})
+
+	t.Run("messages match definitions", func(t *testing.T) {
+		for _, file := range files {
+			// definitions are prefixed with the version, e.g. v1GenerateSequenceResponse
+			version := string(file.Package().Name())
+			messages := file.Messages()
+			for i := range messages.Len() {
+				message := messages.Get(i)
+				def, ok := spec.Definitions[version+string(message.Name())]
+				if !ok {
+					continue // messages used only as query parameters have no definition
+				}
+
+				fields := message.Fields()
+				require.Len(t, def.Properties, fields.Len(), "definition of %s is stale", message.FullName())
+				for j := range fields.Len() {
+					require.Contains(t, def.Properties, fields.Get(j).JSONName())
+				}
+			}
+		}
+	})
+}
+
+func TestRegisterOpenAPI(t *testing.T) {
+	mux := runtime.NewServeMux()
+	require.NoError(t, RegisterOpenAPI(mux))
+
+	tests := map[string]struct {
+		path        string
+		contentType string
+	}{
+		"document": {
+			path:        "/openapi.json",
+			contentType: "application/json",
+		},
+		"explorer": {
+			path:        "/docs",
+			contentType: "text/html; charset=utf-8",
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, data.path, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, data.contentType, rec.Header().Get("Content-Type"))
+			require.NotEmpty(t, rec.Body.Bytes())
+		})
+	}
+}
+
+func binding(rule *annotations.HttpRule) (string, string) {
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return "get", pattern.Get
+	case *annotations.HttpRule_Put:
+		return "put", pattern.Put
+	case *annotations.HttpRule_Post:
+		return "post", pattern.Post
+	case *annotations.HttpRule_Delete:
+		return "delete", pattern.Delete
+	case *annotations.HttpRule_Patch:
+		return "patch", pattern.Patch
+	case *annotations.HttpRule_Custom:
+		return strings.ToLower(pattern.Custom.GetKind()), pattern.Custom.GetPath()
+	default:
+		return "", ""
+	}
+}
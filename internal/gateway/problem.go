@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	rpccode "google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ProblemContentType is the media type of RFC 9457 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem describes an error as RFC 9457 problem details, extended with the grpc code and field violations.
+type Problem struct {
+	Type       string      `json:"type"`
+	Title      string      `json:"title"`
+	Status     int         `json:"status"`
+	Detail     string      `json:"detail,omitempty"`
+	Instance   string      `json:"instance,omitempty"`
+	Code       string      `json:"code"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Violation is a single invalid field of the request.
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// problemHandler renders errors as problem details, while leaving headers and status codes to the default handler.
+func problemHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	m := problemMarshaler{Marshaler: marshaler, instance: r.URL.Path}
+	// routing errors carry their own HTTP status, such as 405 for unsupported methods
+	var custom *runtime.HTTPStatusError
+	if errors.As(err, &custom) {
+		m.status = custom.HTTPStatus
+	}
+
+	runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+}
+
+// problemMarshaler marshals grpc statuses as problem details and delegates anything else.
+type problemMarshaler struct {
+	runtime.Marshaler
+
+	instance string
+	status   int
+}
+
+func (m problemMarshaler) ContentType(v any) string {
+	if _, ok := v.(*spb.Status); ok {
+		return ProblemContentType
+	}
+
+	return m.Marshaler.ContentType(v)
+}
+
+func (m problemMarshaler) Marshal(v any) ([]byte, error) {
+	s, ok := v.(*spb.Status)
+	if !ok {
+		return m.Marshaler.Marshal(v)
+	}
+
+	p := NewProblem(status.FromProto(s), m.instance)
+	if m.status != 0 {
+		p.Status = m.status
+		p.Title = http.StatusText(m.status)
+	}
+
+	return json.Marshal(p)
+}
+
+// NewProblem converts the grpc status of a request to the given path into problem details.
+func NewProblem(s *status.Status, instance string) Problem {
+	code := runtime.HTTPStatusFromCode(s.Code())
+	p := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(code),
+		Status:   code,
+		Detail:   s.Message(),
+		Instance: instance,
+		Code:     codeName(s.Code()),
+	}
+
+	for _, detail := range s.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				p.Violations = append(p.Violations, Violation{
+					Field:   v.GetField(),
+					Rule:    v.GetReason(),
+					Message: v.GetDescription(),
+				})
+			}
+		}
+	}
+
+	return p
+}
+
+// codeName returns the canonical name of the code, as used by google.rpc.Code.
+func codeName(c codes.Code) string {
+	if name, ok := rpccode.Code_name[int32(c)]; ok {
+		return name
+	}
+
+	return c.String()
+}
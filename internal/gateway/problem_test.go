@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/domust/fibonacci/internal/auth"
+	rpc "github.com/domust/fibonacci/internal/grpc"
+)
+
+func TestProblem(t *testing.T) {
+	mux := proxy(t)
+
+	tests := map[string]struct {
+		method  string
+		target  string
+		problem Problem
+	}{
+		"too short": {
+			method: http.MethodGet,
+			target: "/api/v1/generate?length=0",
+			problem: Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "invalid GenerateSequenceRequest: length: value must be greater than 0 and less than 95",
+				Instance: "/api/v1/generate",
+				Code:     "INVALID_ARGUMENT",
+				Violations: []Violation{
+					{Field: "length", Rule: "uint32.gt_lt", Message: "value must be greater than 0 and less than 95"},
+				},
+			},
+		},
+		"overflow": {
+			method: http.MethodGet,
+			target: "/api/v1/generate?length=95",
+			problem: Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "invalid GenerateSequenceRequest: length: value must be greater than 0 and less than 95",
+				Instance: "/api/v1/generate",
+				Code:     "INVALID_ARGUMENT",
+				Violations: []Violation{
+					{Field: "length", Rule: "uint32.gt_lt", Message: "value must be greater than 0 and less than 95"},
+				},
+			},
+		},
+		"unknown path": {
+			method: http.MethodGet,
+			target: "/api/v1/unknown",
+			problem: Problem{
+				Type:     "about:blank",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "Not Found",
+				Instance: "/api/v1/unknown",
+				Code:     "NOT_FOUND",
+			},
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(data.method, data.target, nil))
+			require.Equal(t, data.problem.Status, rec.Code)
+			require.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			require.Equal(t, data.problem, problem)
+		})
+	}
+
+	t.Run("headers", func(t *testing.T) {
+		keys := filepath.Join(t.TempDir(), "keys.json")
+		require.NoError(t, os.WriteFile(keys, []byte(`[{"name": "dashboard", "key": "secret"}]`), 0o600))
+		authenticator, err := auth.New(auth.WithAPIKeys(keys))
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		proxy(t, rpc.WithAuth(authenticator)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/generate?length=3", nil))
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, "missing credentials", rec.Header().Get("WWW-Authenticate"))
+	})
+}
@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutes(t *testing.T) {
+	mux := proxy(t)
+
+	tests := map[string]struct {
+		method string
+		target string
+		body   string
+		code   int
+		want   string
+	}{
+		"query parameter": {
+			method: http.MethodGet,
+			target: "/api/v1/generate?length=5",
+			code:   http.StatusOK,
+			want:   `{"sequence":["0","1","1","2","3"]}`,
+		},
+		"sequence by length": {
+			method: http.MethodGet,
+			target: "/api/v1/sequences/5",
+			code:   http.StatusOK,
+			want:   `{"sequence":["0","1","1","2","3"]}`,
+		},
+		"invalid sequence length": {
+			method: http.MethodGet,
+			target: "/api/v1/sequences/95",
+			code:   http.StatusBadRequest,
+		},
+		"malformed sequence length": {
+			method: http.MethodGet,
+			target: "/api/v1/sequences/five",
+			code:   http.StatusBadRequest,
+		},
+		"generate": {
+			method: http.MethodPost,
+			target: "/api/v1/sequences:generate",
+			body:   `{"length": 5}`,
+			code:   http.StatusOK,
+			want:   `{"sequence":["0","1","1","2","3"]}`,
+		},
+		"generate with invalid body": {
+			method: http.MethodPost,
+			target: "/api/v1/sequences:generate",
+			body:   `{"length": "five"}`,
+			code:   http.StatusBadRequest,
+		},
+		"number by index": {
+			method: http.MethodGet,
+			target: "/api/v1/numbers/93",
+			code:   http.StatusOK,
+			want:   `{"number":"12200160415121876738"}`,
+		},
+		"first number": {
+			method: http.MethodGet,
+			target: "/api/v1/numbers/0",
+			code:   http.StatusOK,
+			want:   `{"number":"0"}`,
+		},
+		"overflowing number": {
+			method: http.MethodGet,
+			target: "/api/v1/numbers/94",
+			code:   http.StatusBadRequest,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(data.method, data.target, strings.NewReader(data.body))
+			if data.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			require.Equal(t, data.code, rec.Code, rec.Body.String())
+			if data.want != "" {
+				require.JSONEq(t, data.want, rec.Body.String())
+			} else {
+				require.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/domust/fibonacci/api"
+)
+
+// WebSocketPath is the path of the endpoint streaming sequences over WebSockets.
+const WebSocketPath = "/api/v1/generate/ws"
+
+// closeCodeOffset is added to grpc status codes in order to get close codes from the range reserved for applications,
+// e.g. 4003 is sent for invalid arguments.
+const closeCodeOffset = 4000
+
+// maxCloseReason is the length of close reasons allowed by the WebSocket protocol.
+const maxCloseReason = 123
+
+// WebSocketOptions tune the lifetime of WebSocket connections.
+type WebSocketOptions struct {
+	// Origins allowed to open connections in addition to the origin of the gateway itself, in the form of CORS origins.
+	Origins []string
+	// RequestTimeout is how long clients are given to send the generation request after connecting.
+	RequestTimeout time.Duration
+	// PingInterval is how often clients are pinged, connections are closed when pongs do not arrive in time.
+	PingInterval time.Duration
+	// WriteTimeout is how long clients are given to receive a single frame, slower clients are disconnected.
+	WriteTimeout time.Duration
+}
+
+// RegisterWebSocket streams sequences to browsers, which cannot consume grpc server streams.
+// Clients send a single JSON generation request, such as {"length":10}, after which a JSON frame is sent per term,
+// e.g. {"index":9,"value":"34"}. Connections are closed with 1000 once the sequence is complete,
+// or with 4000 plus the grpc status code on failure, such as 4003 for invalid requests.
+func RegisterWebSocket(mux *runtime.ServeMux, client api.FibonacciClient, opts WebSocketOptions) error {
+	accept := &websocket.AcceptOptions{OriginPatterns: opts.Origins}
+	// any origin is allowed by skipping verification altogether, since origin patterns do not support it
+	if slices.Contains(opts.Origins, "*") {
+		accept = &websocket.AcceptOptions{InsecureSkipVerify: true}
+	}
+
+	err := mux.HandlePath(http.MethodGet, WebSocketPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		// deadlines of the HTTP server are meant for requests, not for long-lived connections
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		conn, err := websocket.Accept(w, r, accept)
+		if err != nil {
+			return // the handshake has already been rejected
+		}
+		defer func() { _ = conn.CloseNow() }()
+
+		streamSequence(r, conn, mux, client, opts)
+	})
+	if err != nil {
+		return fmt.Errorf("websocket %s: %w", WebSocketPath, err)
+	}
+
+	return nil
+}
+
+// streamSequence proxies a single grpc stream to the connection.
+func streamSequence(r *http.Request, conn *websocket.Conn, mux *runtime.ServeMux, client api.FibonacciClient, opts WebSocketOptions) {
+	conn.SetReadLimit(1024) // requests consist of a single field
+
+	req, err := readRequest(r.Context(), conn, opts.RequestTimeout)
+	if err != nil {
+		closeWithError(conn, err)
+		return
+	}
+
+	// reading continues in the background in order to answer pings, and the context is cancelled once the client leaves
+	ctx, cancel := context.WithCancel(conn.CloseRead(r.Context()))
+	defer cancel()
+
+	ctx, err = runtime.AnnotateContext(ctx, mux, r, api.Fibonacci_StreamSequence_FullMethodName, runtime.WithHTTPPathPattern(WebSocketPath))
+	if err != nil {
+		closeWithError(conn, err)
+		return
+	}
+
+	stream, err := client.StreamSequence(ctx, req)
+	if err != nil {
+		closeWithError(conn, err)
+		return
+	}
+
+	if opts.PingInterval > 0 {
+		go keepAlive(ctx, cancel, conn, opts.PingInterval)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			_ = conn.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+		if err != nil {
+			closeWithError(conn, err)
+			return
+		}
+
+		frame, err := defaultMarshaler.Marshal(resp)
+		if err != nil {
+			closeWithError(conn, err)
+			return
+		}
+
+		// the next term is only received once the client has taken the previous one,
+		// so that slow clients hold back generation instead of frames piling up in memory
+		if err := write(ctx, conn, frame, opts.WriteTimeout); err != nil {
+			return // failed writes close the connection
+		}
+	}
+}
+
+// readRequest reads the generation request, which is the first and only message sent by clients.
+func readRequest(ctx context.Context, conn *websocket.Conn, timeout time.Duration) (*api.GenerateSequenceRequest, error) {
+	// the connection is closed by the timer rather than by the context,
+	// because cancelled reads tear the connection down without a close code
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			closeWithError(conn, status.Error(codes.DeadlineExceeded, "request was not sent in time"))
+		})
+		defer timer.Stop()
+	}
+
+	typ, data, err := conn.Read(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "reading request: %v", err)
+	}
+	if typ != websocket.MessageText {
+		return nil, status.Error(codes.InvalidArgument, "request must be a text message")
+	}
+
+	var req api.GenerateSequenceRequest
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "malformed request: %v", err)
+	}
+
+	return &req, nil
+}
+
+func write(ctx context.Context, conn *websocket.Conn, frame []byte, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return conn.Write(ctx, websocket.MessageText, frame)
+}
+
+// keepAlive pings the client periodically and gives up on the stream once a pong does not arrive within the interval.
+func keepAlive(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, pingCancel := context.WithTimeout(ctx, interval)
+		err := conn.Ping(pingCtx)
+		pingCancel()
+		if err != nil {
+			cancel()
+			_ = conn.Close(websocket.StatusPolicyViolation, "pong was not received in time")
+			return
+		}
+	}
+}
+
+// closeWithError closes the connection with the close code of the grpc status of the error.
+func closeWithError(conn *websocket.Conn, err error) {
+	s := status.Convert(err)
+	reason := s.Message()
+	if len(reason) > maxCloseReason {
+		reason = reason[:maxCloseReason]
+	}
+
+	_ = conn.Close(CloseCode(s.Code()), reason)
+}
+
+// CloseCode returns the close code sent when streams fail with the grpc code.
+func CloseCode(code codes.Code) websocket.StatusCode {
+	return websocket.StatusCode(closeCodeOffset + int(code))
+}
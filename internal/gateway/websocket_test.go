@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal/caching"
+	"github.com/domust/fibonacci/internal/compression"
+)
+
+// websocketURL serves the WebSocket endpoint with the given options behind the middlewares and returns its URL.
+func websocketURL(t *testing.T, opts WebSocketOptions, middlewares ...Middleware) string {
+	t.Helper()
+
+	mux := NewServeMux()
+	require.NoError(t, RegisterWebSocket(mux, api.NewFibonacciClient(dial(t)), opts))
+
+	srv := httptest.NewServer(Chain(mux, middlewares...))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + WebSocketPath
+}
+
+func TestWebSocket(t *testing.T) {
+	opts := WebSocketOptions{RequestTimeout: 100 * time.Millisecond, PingInterval: time.Second, WriteTimeout: time.Second}
+
+	tests := map[string]struct {
+		middlewares []Middleware
+		message     websocket.MessageType
+		request     string
+		frames      int
+		code        websocket.StatusCode
+		reason      string
+	}{
+		"sequence": {
+			message: websocket.MessageText,
+			request: `{"length":5}`,
+			frames:  5,
+			code:    websocket.StatusNormalClosure,
+		},
+		"behind middlewares": {
+			middlewares: []Middleware{caching.Middleware, compression.New(1, nil).Middleware, Format},
+			message:     websocket.MessageText,
+			request:     `{"length":94}`,
+			frames:      94,
+			code:        websocket.StatusNormalClosure,
+		},
+		"invalid request": {
+			message: websocket.MessageText,
+			request: `{"length":95}`,
+			code:    CloseCode(codes.InvalidArgument),
+			reason:  "length: value must be greater than 0 and less than 95",
+		},
+		"malformed request": {
+			message: websocket.MessageText,
+			request: `{"length":`,
+			code:    CloseCode(codes.InvalidArgument),
+			reason:  "malformed request",
+		},
+		"binary request": {
+			message: websocket.MessageBinary,
+			request: `{"length":5}`,
+			code:    CloseCode(codes.InvalidArgument),
+			reason:  "request must be a text message",
+		},
+		"missing request": {
+			code:   CloseCode(codes.DeadlineExceeded),
+			reason: "request was not sent in time",
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			conn, _, err := websocket.Dial(ctx, websocketURL(t, opts, data.middlewares...), &websocket.DialOptions{
+				HTTPHeader: http.Header{"Accept-Encoding": {"gzip"}},
+			})
+			require.NoError(t, err)
+			defer func() { _ = conn.CloseNow() }()
+
+			if data.request != "" {
+				require.NoError(t, conn.Write(ctx, data.message, []byte(data.request)))
+			}
+
+			seq := []uint64{0, 1}
+			for i := range data.frames {
+				typ, frame, err := conn.Read(ctx)
+				require.NoError(t, err)
+				require.Equal(t, websocket.MessageText, typ)
+
+				if i >= len(seq) {
+					seq = append(seq, seq[i-1]+seq[i-2])
+				}
+				require.JSONEq(t, fmt.Sprintf(`{"index":%d,"value":"%d"}`, i, seq[i]), string(frame))
+			}
+
+			_, _, err = conn.Read(ctx)
+			require.Equal(t, data.code, websocket.CloseStatus(err))
+			var closeErr websocket.CloseError
+			require.ErrorAs(t, err, &closeErr)
+			require.Contains(t, closeErr.Reason, data.reason)
+		})
+	}
+}
+
+func TestWebSocketOrigins(t *testing.T) {
+	tests := map[string]struct {
+		origins []string
+		origin  string
+		allowed bool
+	}{
+		"same origin":       {allowed: true},
+		"disallowed origin": {origin: "https://evil.example.com"},
+		"allowed origin":    {origins: []string{"https://dashboard.example.com"}, origin: "https://dashboard.example.com", allowed: true},
+		"wildcard subdomain": {
+			origins: []string{"https://*.example.com"},
+			origin:  "https://dashboard.example.com",
+			allowed: true,
+		},
+		"any origin": {origins: []string{"*"}, origin: "https://evil.example.com", allowed: true},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{}
+			if data.origin != "" {
+				header.Set("Origin", data.origin)
+			}
+
+			conn, resp, err := websocket.Dial(context.Background(), websocketURL(t, WebSocketOptions{Origins: data.origins}), &websocket.DialOptions{
+				HTTPHeader: header,
+			})
+			if !data.allowed {
+				require.Error(t, err)
+				require.Equal(t, http.StatusForbidden, resp.StatusCode)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, conn.Close(websocket.StatusNormalClosure, ""))
+		})
+	}
+}
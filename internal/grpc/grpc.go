@@ -2,30 +2,251 @@
 package grpc
 
 import (
+	"context"
+	"log/slog"
+
 	"buf.build/go/protovalidate"
-	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/protovalidate"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 
+	"github.com/domust/fibonacci/internal/accesslog"
+	"github.com/domust/fibonacci/internal/admission"
+	"github.com/domust/fibonacci/internal/auth"
+	"github.com/domust/fibonacci/internal/authz"
+	"github.com/domust/fibonacci/internal/budget"
+	"github.com/domust/fibonacci/internal/caching"
+	"github.com/domust/fibonacci/internal/compression"
+	"github.com/domust/fibonacci/internal/idempotency"
+	"github.com/domust/fibonacci/internal/ratelimit"
+	"github.com/domust/fibonacci/internal/recovery"
 	"github.com/domust/fibonacci/internal/telemetry"
+	"github.com/domust/fibonacci/internal/validation"
 )
 
+// Option configures optional server features.
+type Option func(*options)
+
+type options struct {
+	reflection bool
+	accessLog  *accesslog.Logger
+	compressor *compression.Compression
+	cache      *caching.Cache
+	limiter    *ratelimit.Limiter
+	admission  *admission.Controller
+	auth       *auth.Authenticator
+	authz      *authz.Authorizer
+	budget     *budget.Budget
+	recoverer  *recovery.Recoverer
+	idempotent *idempotency.Store
+}
+
+// interceptor pairs unary and stream variants of the same middleware.
+type interceptor struct {
+	unary  grpc.UnaryServerInterceptor
+	stream grpc.StreamServerInterceptor
+}
+
+// WithReflection registers v1 and v1alpha server reflection services when enabled.
+func WithReflection(enabled bool) Option {
+	return func(o *options) {
+		o.reflection = enabled
+	}
+}
+
+// WithAccessLog logs every call once it completes, including calls rejected by later interceptors.
+func WithAccessLog(logger *accesslog.Logger) Option {
+	return func(o *options) {
+		o.accessLog = logger
+	}
+}
+
+// WithCompression leaves responses below the compression threshold uncompressed, even when clients accept compression.
+func WithCompression(c *compression.Compression) Option {
+	return func(o *options) {
+		o.compressor = c
+	}
+}
+
+// WithCaching marks successful responses as immutable with an entity tag derived from the request.
+func WithCaching(cache *caching.Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithRateLimit rejects calls exceeding per-client limits once clients are authenticated, but before they are validated.
+func WithRateLimit(limiter *ratelimit.Limiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithAdmission bounds the cost of valid calls handled concurrently and sheds load when the server is overloaded.
+func WithAdmission(controller *admission.Controller) Option {
+	return func(o *options) {
+		o.admission = controller
+	}
+}
+
+// WithAuth rejects unauthenticated calls and propagates the authenticated principal through the context.
+func WithAuth(authenticator *auth.Authenticator) Option {
+	return func(o *options) {
+		o.auth = authenticator
+	}
+}
+
+// WithAuthz denies calls not allowed by authorization policies, once requests are known to be valid.
+func WithAuthz(authorizer *authz.Authorizer) Option {
+	return func(o *options) {
+		o.authz = authorizer
+	}
+}
+
+// WithBudget rejects calls that cannot finish within their deadline or the maximum budget.
+func WithBudget(b *budget.Budget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
+
+// WithRecovery replaces the default recoverer, which only logs panics, in order to report them in metrics too.
+func WithRecovery(recoverer *recovery.Recoverer) Option {
+	return func(o *options) {
+		o.recoverer = recoverer
+	}
+}
+
+// WithIdempotency answers calls retried with the same idempotency key with the response of the first call.
+func WithIdempotency(store *idempotency.Store) Option {
+	return func(o *options) {
+		o.idempotent = store
+	}
+}
+
 // NewServer is a wrapper around [google.golang.org/grpc.NewServer] to ensure that
 // server configuration is identical between production and test servers.
 func NewServer(
 	telemetry *telemetry.Telemetry,
 	validator protovalidate.Validator,
+	opts ...Option,
 ) *grpc.Server {
-	var opts []grpc.ServerOption
+	o := newOptions(telemetry, opts)
+
+	var serverOpts []grpc.ServerOption
 	if telemetry != nil {
-		opts = append(opts, telemetry.ServerOption())
+		serverOpts = append(serverOpts, telemetry.ServerOption())
+	}
+
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+	for _, i := range o.chain(telemetry, validator) {
+		unary = append(unary, i.unary)
+		stream = append(stream, i.stream)
+	}
+	serverOpts = append(serverOpts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+
+	s := grpc.NewServer(serverOpts...)
+	if o.reflection {
+		reflection.Register(s)
+	}
+
+	return s
+}
+
+// UnaryInterceptors returns the unary interceptors of a server created with the same arguments,
+// so that calls served without grpc transport, such as JSON-RPC calls, are handled identically.
+func UnaryInterceptors(
+	telemetry *telemetry.Telemetry,
+	validator protovalidate.Validator,
+	opts ...Option,
+) []grpc.UnaryServerInterceptor {
+	o := newOptions(telemetry, opts)
+
+	var unary []grpc.UnaryServerInterceptor
+	for _, i := range o.chain(telemetry, validator) {
+		unary = append(unary, i.unary)
 	}
 
-	var interceptors []grpc.UnaryServerInterceptor
+	return unary
+}
+
+// ChainUnaryInterceptors runs the interceptors in order, the first of which is the outermost one,
+// the same way as [google.golang.org/grpc.ChainUnaryInterceptor] does for calls served over grpc.
+func ChainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], handler
+			handler = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func newOptions(telemetry *telemetry.Telemetry, opts []Option) *options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.recoverer == nil {
+		var logger *slog.Logger
+		if telemetry != nil {
+			logger = telemetry.Logger()
+		}
+		o.recoverer = recovery.New(logger, nil)
+	}
+
+	return &o
+}
+
+// chain lists interceptors in the order they run, so that unary and stream chains are built from the same list.
+func (o *options) chain(telemetry *telemetry.Telemetry, validator protovalidate.Validator) []interceptor {
+	var chain []interceptor
 	if telemetry != nil {
-		interceptors = append(interceptors, telemetry.UnaryInterceptor())
+		chain = append(chain, interceptor{telemetry.UnaryInterceptor(), telemetry.StreamInterceptor()})
+	}
+	// access log follows telemetry, so that records carry trace and span IDs
+	if o.accessLog != nil {
+		chain = append(chain, interceptor{o.accessLog.UnaryInterceptor(), o.accessLog.StreamInterceptor()})
+	}
+	// recovery follows telemetry, so that panics are recorded on the active span
+	chain = append(chain, interceptor{o.recoverer.UnaryInterceptor(), o.recoverer.StreamInterceptor()})
+	if o.compressor != nil {
+		chain = append(chain, interceptor{o.compressor.UnaryInterceptor(), o.compressor.StreamInterceptor()})
+	}
+	if o.cache != nil {
+		chain = append(chain, interceptor{o.cache.UnaryInterceptor(), o.cache.StreamInterceptor()})
+	}
+	if o.auth != nil {
+		chain = append(chain, interceptor{o.auth.UnaryInterceptor(), o.auth.StreamInterceptor()})
+	}
+	// rate limiting follows authentication, so that clients are identified by verified credentials only
+	if o.limiter != nil {
+		chain = append(chain, interceptor{o.limiter.UnaryInterceptor(), o.limiter.StreamInterceptor()})
+	}
+	v := validation.New(validator)
+	chain = append(chain, interceptor{v.UnaryInterceptor(), v.StreamInterceptor()})
+	if o.authz != nil {
+		chain = append(chain, interceptor{o.authz.UnaryInterceptor(), o.authz.StreamInterceptor()})
+	}
+	// idempotency follows authorization, so that responses are only replayed to clients allowed to call the method,
+	// and precedes the budget, so that replays cost nothing
+	if o.idempotent != nil {
+		chain = append(chain, interceptor{o.idempotent.UnaryInterceptor(), o.idempotent.StreamInterceptor()})
+	}
+	// admission follows rate limiting, so that abusive clients cannot occupy the queue, and validation, so that
+	// costs are estimated from lengths within bounds, while replayed calls do not occupy capacity at all
+	if o.admission != nil {
+		chain = append(chain, interceptor{o.admission.UnaryInterceptor(), o.admission.StreamInterceptor()})
+	}
+	// budget is checked last, so that the maximum budget bounds the handler alone
+	if o.budget != nil {
+		chain = append(chain, interceptor{o.budget.UnaryInterceptor(), o.budget.StreamInterceptor()})
 	}
-	interceptors = append(interceptors, middleware.UnaryServerInterceptor(validator))
-	opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
 
-	return grpc.NewServer(opts...)
+	return chain
 }
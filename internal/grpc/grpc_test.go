@@ -0,0 +1,239 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"buf.build/go/protovalidate"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal"
+	"github.com/domust/fibonacci/internal/auth"
+	"github.com/domust/fibonacci/internal/authz"
+)
+
+// dial starts the server on an in-memory listener and returns a client connection to it.
+func dial(t *testing.T, s *grpc.Server) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough://", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestReflection(t *testing.T) {
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+
+	server := func(enabled bool) *grpc.ClientConn {
+		s := NewServer(nil, validator, WithReflection(enabled))
+		api.RegisterFibonacciServer(s, api.UnimplementedFibonacciServer{})
+		return dial(t, s)
+	}
+
+	t.Run("v1", func(t *testing.T) {
+		stream, err := reflectionv1.NewServerReflectionClient(server(true)).ServerReflectionInfo(context.Background())
+		require.NoError(t, err)
+
+		require.NoError(t, stream.Send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+		}))
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+
+		var services []string
+		for _, service := range resp.GetListServicesResponse().GetService() {
+			services = append(services, service.GetName())
+		}
+		require.Contains(t, services, api.Fibonacci_ServiceDesc.ServiceName)
+	})
+
+	t.Run("v1alpha", func(t *testing.T) {
+		stream, err := reflectionv1alpha.NewServerReflectionClient(server(true)).ServerReflectionInfo(context.Background())
+		require.NoError(t, err)
+
+		require.NoError(t, stream.Send(&reflectionv1alpha.ServerReflectionRequest{
+			MessageRequest: &reflectionv1alpha.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: api.Fibonacci_ServiceDesc.ServiceName,
+			},
+		}))
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.GetFileDescriptorResponse().GetFileDescriptorProto())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		stream, err := reflectionv1.NewServerReflectionClient(server(false)).ServerReflectionInfo(context.Background())
+		require.NoError(t, err)
+
+		_, err = stream.Recv()
+		require.Equal(t, codes.Unimplemented.String(), status.Code(err).String())
+	})
+}
+
+// echo is a bidirectional streaming service used to verify that stream interceptors behave like unary ones,
+// which replies to every request with the generated sequence.
+var echo = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Echo",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(_ any, stream grpc.ServerStream) error {
+			for {
+				var req api.GenerateSequenceRequest
+				if err := stream.RecvMsg(&req); err != nil {
+					return err
+				}
+
+				resp, err := internal.NewServer(nil).GenerateSequence(stream.Context(), &req)
+				if err != nil {
+					return err
+				}
+
+				if err := stream.SendMsg(resp); err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+func TestStreamParity(t *testing.T) {
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+
+	keys := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(keys, []byte(`[{"name": "dashboard", "key": "secret"}]`), 0o600))
+	authenticator, err := auth.New(auth.WithAPIKeys(keys))
+	require.NoError(t, err)
+	authorizer, err := authz.New([]authz.Policy{{Name: "short", Methods: []string{authz.AnyMethod}, Condition: "request.length < 10"}})
+	require.NoError(t, err)
+
+	opts := []Option{WithAuth(authenticator), WithAuthz(authorizer)}
+	s := NewServer(nil, validator, opts...)
+	api.RegisterFibonacciServer(s, internal.NewServer(nil))
+	s.RegisterService(&echo, nil)
+	conn := dial(t, s)
+
+	unary := func(ctx context.Context, req *api.GenerateSequenceRequest) error {
+		_, err := api.NewFibonacciClient(conn).GenerateSequence(ctx, req)
+		return err
+	}
+	stream := func(ctx context.Context, req *api.GenerateSequenceRequest) error {
+		stream, err := conn.NewStream(ctx, &echo.Streams[0], "/test.Echo/Echo")
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(req); err != nil {
+			return err
+		}
+		return stream.RecvMsg(&api.GenerateSequenceResponse{})
+	}
+
+	// calls served in-process, such as JSON-RPC calls, are intercepted by the same chain
+	chain := ChainUnaryInterceptors(UnaryInterceptors(nil, validator, opts...)...)
+	inProcess := func(ctx context.Context, req *api.GenerateSequenceRequest) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		info := &grpc.UnaryServerInfo{FullMethod: api.Fibonacci_GenerateSequence_FullMethodName}
+		_, err := chain(metadata.NewIncomingContext(ctx, md), req, info, func(ctx context.Context, req any) (any, error) {
+			return internal.NewServer(nil).GenerateSequence(ctx, req.(*api.GenerateSequenceRequest))
+		})
+		return err
+	}
+
+	authenticated := metadata.AppendToOutgoingContext(context.Background(), auth.APIKeyHeader, "secret")
+	tests := map[string]struct {
+		ctx  context.Context
+		req  *api.GenerateSequenceRequest
+		code codes.Code
+	}{
+		"valid": {
+			ctx:  authenticated,
+			req:  &api.GenerateSequenceRequest{Length: 5},
+			code: codes.OK,
+		},
+		"invalid": {
+			ctx:  authenticated,
+			req:  &api.GenerateSequenceRequest{},
+			code: codes.InvalidArgument,
+		},
+		"unauthorized": {
+			ctx:  authenticated,
+			req:  &api.GenerateSequenceRequest{Length: 50},
+			code: codes.PermissionDenied,
+		},
+		"authentication precedes validation": {
+			ctx:  context.Background(),
+			req:  &api.GenerateSequenceRequest{},
+			code: codes.Unauthenticated,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, data.code.String(), status.Code(unary(data.ctx, data.req)).String(), "unary")
+			require.Equal(t, data.code.String(), status.Code(stream(data.ctx, data.req)).String(), "stream")
+			require.Equal(t, data.code.String(), status.Code(inProcess(data.ctx, data.req)).String(), "in-process")
+		})
+	}
+
+	t.Run("every inbound message is validated", func(t *testing.T) {
+		stream, err := conn.NewStream(authenticated, &echo.Streams[0], "/test.Echo/Echo")
+		require.NoError(t, err)
+
+		require.NoError(t, stream.SendMsg(&api.GenerateSequenceRequest{Length: 3}))
+		var resp api.GenerateSequenceResponse
+		require.NoError(t, stream.RecvMsg(&resp))
+		require.Equal(t, []uint64{0, 1, 1}, resp.GetSequence())
+
+		require.NoError(t, stream.SendMsg(&api.GenerateSequenceRequest{Length: 95}))
+		err = stream.RecvMsg(&resp)
+		require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+	})
+}
+
+func TestRecovery(t *testing.T) {
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+
+	s := NewServer(nil, validator)
+	api.RegisterFibonacciServer(s, panicking{})
+	client := api.NewFibonacciClient(dial(t, s))
+
+	for range 2 {
+		_, err := client.GenerateSequence(context.Background(), &api.GenerateSequenceRequest{Length: 1})
+		require.Equal(t, codes.Internal.String(), status.Code(err).String())
+	}
+}
+
+type panicking struct {
+	api.UnimplementedFibonacciServer
+}
+
+func (panicking) GenerateSequence(context.Context, *api.GenerateSequenceRequest) (*api.GenerateSequenceResponse, error) {
+	panic("boom")
+}
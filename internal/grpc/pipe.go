@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/domust/fibonacci/internal/ratelimit"
+)
+
+// pipeSize is the size of the buffer of each direction of a connection.
+const pipeSize = 1024 * 1024
+
+// Pipe is an in-process listener, which connects the gateway to the grpc server without going through the network.
+// Calls made over it are marked with [ratelimit.ProxyAddr] as their peer address, since no other process can make them,
+// so that they are identified by the address of the HTTP client forwarded by the gateway.
+type Pipe struct {
+	*bufconn.Listener
+}
+
+// NewPipe returns an in-process listener.
+func NewPipe() *Pipe {
+	return &Pipe{Listener: bufconn.Listen(pipeSize)}
+}
+
+// Accept returns the next connection of the gateway.
+func (p *Pipe) Accept() (net.Conn, error) {
+	conn, err := p.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return proxyConn{conn}, nil
+}
+
+// DialOption makes clients connect over the pipe, regardless of their target.
+func (p *Pipe) DialOption() grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return p.DialContext(ctx)
+	})
+}
+
+// proxyConn is the server side of a connection of the gateway.
+type proxyConn struct {
+	net.Conn
+}
+
+func (proxyConn) RemoteAddr() net.Addr {
+	return ratelimit.ProxyAddr
+}
@@ -0,0 +1,172 @@
+// Package health tracks the health of the service and reports it to grpc health clients and HTTP probes.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Kind determines how a failing check affects the health of the service.
+type Kind int
+
+const (
+	// Readiness checks take the service out of rotation while failing.
+	Readiness Kind = iota
+	// Liveness checks indicate that the process is broken and has to be restarted.
+	Liveness
+	// Informational checks are reported, but do not affect the health of the service.
+	Informational
+)
+
+// Check verifies a single dependency or capability of the service.
+type Check struct {
+	Name  string
+	Kind  Kind
+	Check func(context.Context) error
+}
+
+// Report is a snapshot of the health of the service.
+type Report struct {
+	// Started is set once all liveness and readiness checks have passed for the first time.
+	Started bool `json:"started"`
+	// Live is unset when any of the liveness checks fails.
+	Live bool `json:"live"`
+	// Ready is set when the service is started, not draining and all readiness checks pass.
+	Ready bool `json:"ready"`
+	// Draining is set once the service has started shutting down.
+	Draining bool `json:"draining"`
+	// Checks contains the outcome of every check, keyed by the check's name.
+	Checks map[string]string `json:"checks"`
+}
+
+// Health evaluates checks periodically and propagates the outcome to the grpc health server.
+type Health struct {
+	server   *health.Server
+	services []string
+	checks   []Check
+
+	mu       sync.RWMutex
+	started  bool
+	draining bool
+	results  map[string]error
+}
+
+// New returns health reporting NOT_SERVING for the given services until startup completes.
+// The overall health of the server is reported under the empty service name.
+func New(server *health.Server, services ...string) *Health {
+	h := &Health{
+		server:   server,
+		services: append([]string{""}, services...),
+		results:  make(map[string]error),
+	}
+	h.set(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	return h
+}
+
+// Add registers a check. It must be called before [Health.Run].
+func (h *Health) Add(checks ...Check) {
+	h.checks = append(h.checks, checks...)
+}
+
+// Run evaluates checks immediately and then on every interval until ctx is done.
+// Each check is given at most timeout to complete.
+func (h *Health) Run(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.evaluate(ctx, timeout)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown reports NOT_SERVING for all services and ignores any further check results.
+func (h *Health) Shutdown() {
+	h.mu.Lock()
+	h.draining = true
+	h.mu.Unlock()
+
+	h.server.Shutdown()
+}
+
+// Report returns the current health of the service.
+func (h *Health) Report() Report {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	report := Report{
+		Started:  h.started,
+		Live:     h.passing(Liveness),
+		Draining: h.draining,
+		Checks:   make(map[string]string, len(h.results)),
+	}
+	report.Ready = h.ready()
+
+	for name, err := range h.results {
+		report.Checks[name] = "ok"
+		if err != nil {
+			report.Checks[name] = err.Error()
+		}
+	}
+
+	return report
+}
+
+func (h *Health) evaluate(ctx context.Context, timeout time.Duration) {
+	results := make(map[string]error, len(h.checks))
+	for _, check := range h.checks {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		results[check.Name] = check.Check(ctx)
+		cancel()
+	}
+
+	h.mu.Lock()
+	h.results = results
+	if !h.started && h.passing(Liveness) && h.passing(Readiness) {
+		h.started = true
+	}
+	ready, draining := h.ready(), h.draining
+	h.mu.Unlock()
+
+	if draining {
+		return
+	}
+
+	if ready {
+		h.set(grpc_health_v1.HealthCheckResponse_SERVING)
+	} else {
+		h.set(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+func (h *Health) set(status grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	for _, service := range h.services {
+		h.server.SetServingStatus(service, status)
+	}
+}
+
+// passing must be called with the lock held.
+func (h *Health) passing(kind Kind) bool {
+	for _, check := range h.checks {
+		if check.Kind == kind && h.results[check.Name] != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ready must be called with the lock held.
+func (h *Health) ready() bool {
+	return h.started && !h.draining && h.passing(Liveness) && h.passing(Readiness)
+}
@@ -0,0 +1,133 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const service = "api.v1.Fibonacci"
+
+func TestHealth(t *testing.T) {
+	// status returns the serving status of the given service as seen by grpc health clients.
+	status := func(t *testing.T, server *health.Server, service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		resp, err := server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.GetStatus()
+	}
+
+	// failing returns a check function, which fails while the returned flag is set.
+	failing := func(initial bool) (func(context.Context) error, *bool) {
+		fail := initial
+		return func(context.Context) error {
+			if fail {
+				return errors.New("failure")
+			}
+			return nil
+		}, &fail
+	}
+
+	t.Run("starting", func(t *testing.T) {
+		server := health.NewServer()
+		h := New(server, service)
+
+		report := h.Report()
+		require.False(t, report.Started)
+		require.True(t, report.Live)
+		require.False(t, report.Ready)
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status(t, server, ""))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status(t, server, service))
+	})
+
+	t.Run("startup gating", func(t *testing.T) {
+		server := health.NewServer()
+		h := New(server, service)
+		check, fail := failing(true)
+		h.Add(Check{Name: "dependency", Kind: Readiness, Check: check})
+
+		h.evaluate(context.Background(), time.Second)
+		report := h.Report()
+		require.False(t, report.Started)
+		require.False(t, report.Ready)
+		require.Equal(t, "failure", report.Checks["dependency"])
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status(t, server, service))
+
+		*fail = false
+		h.evaluate(context.Background(), time.Second)
+		report = h.Report()
+		require.True(t, report.Started)
+		require.True(t, report.Ready)
+		require.Equal(t, "ok", report.Checks["dependency"])
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, status(t, server, ""))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, status(t, server, service))
+
+		*fail = true
+		h.evaluate(context.Background(), time.Second)
+		report = h.Report()
+		require.True(t, report.Started)
+		require.True(t, report.Live)
+		require.False(t, report.Ready)
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status(t, server, service))
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		server := health.NewServer()
+		h := New(server, service)
+		check, fail := failing(false)
+		h.Add(Check{Name: "self-test", Kind: Liveness, Check: check})
+
+		h.evaluate(context.Background(), time.Second)
+		require.True(t, h.Report().Live)
+
+		*fail = true
+		h.evaluate(context.Background(), time.Second)
+		report := h.Report()
+		require.True(t, report.Started)
+		require.False(t, report.Live)
+		require.False(t, report.Ready)
+	})
+
+	t.Run("informational", func(t *testing.T) {
+		server := health.NewServer()
+		h := New(server, service)
+		check, _ := failing(true)
+		h.Add(Check{Name: "telemetry", Kind: Informational, Check: check})
+
+		h.evaluate(context.Background(), time.Second)
+		report := h.Report()
+		require.True(t, report.Ready)
+		require.Equal(t, "failure", report.Checks["telemetry"])
+	})
+
+	t.Run("draining", func(t *testing.T) {
+		server := health.NewServer()
+		h := New(server, service)
+
+		h.evaluate(context.Background(), time.Second)
+		require.True(t, h.Report().Ready)
+
+		h.Shutdown()
+		h.evaluate(context.Background(), time.Second)
+		report := h.Report()
+		require.True(t, report.Draining)
+		require.False(t, report.Ready)
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status(t, server, service))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		server := health.NewServer()
+		h := New(server, service)
+		h.Add(Check{Name: "slow", Kind: Readiness, Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+
+		h.evaluate(context.Background(), time.Millisecond)
+		require.Equal(t, context.DeadlineExceeded.Error(), h.Report().Checks["slow"])
+	})
+}
@@ -0,0 +1,66 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/internal/gateway"
+)
+
+// LimitBody rejects requests with bodies larger than limit bytes with 413 Content Too Large.
+// Bodies are read in full before calling the next handler, because the gateway reports
+// failures to read them as invalid arguments, which hides the actual cause.
+func LimitBody(next http.Handler, limit int64) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			tooLarge(w, r, limit)
+			return
+		}
+		if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+		if err != nil {
+			var maxBytes *http.MaxBytesError
+			if errors.As(err, &maxBytes) {
+				tooLarge(w, r, limit)
+				return
+			}
+
+			problem(w, gateway.NewProblem(status.Newf(codes.InvalidArgument, "reading request body: %v", err), r.URL.Path))
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func tooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	p := gateway.NewProblem(status.New(codes.ResourceExhausted, fmt.Sprintf("request body exceeds %d bytes", limit)), r.URL.Path)
+	p.Status = http.StatusRequestEntityTooLarge
+	p.Title = http.StatusText(p.Status)
+	// the rest of the body is left unread, so the connection cannot be reused
+	w.Header().Set("Connection", "close")
+	problem(w, p)
+}
+
+func problem(w http.ResponseWriter, p gateway.Problem) {
+	w.Header().Set("Content-Type", gateway.ProblemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
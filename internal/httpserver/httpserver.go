@@ -0,0 +1,46 @@
+// Package httpserver hardens the HTTP server of the gateway against slow, oversized and excessive requests.
+package httpserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// Limits bound the resources a single client can hold on to.
+// Zero values disable the respective limit.
+type Limits struct {
+	// ReadHeaderTimeout is how long clients are given to send request headers, protecting against slowloris attacks.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout is how long clients are given to send the whole request, including the body.
+	ReadTimeout time.Duration
+	// WriteTimeout is how long handlers are given to write the response, counted from the end of request headers.
+	WriteTimeout time.Duration
+	// IdleTimeout is how long keep-alive connections are kept open between requests.
+	IdleTimeout time.Duration
+	// MaxHeaderBytes is the size of request headers, larger headers are rejected with 431 Request Header Fields Too Large.
+	MaxHeaderBytes int
+	// MaxBodyBytes is the size of request bodies, larger bodies are rejected with 413 Content Too Large.
+	MaxBodyBytes int64
+	// MaxConns is the number of connections served concurrently, further connections wait to be accepted.
+	MaxConns int
+	// MaxConnsPerClient is the number of connections a single client address can open, further connections are closed.
+	MaxConnsPerClient int
+}
+
+// New returns a server of the handler with the given limits.
+// Connection limits are applied by serving a [Listener], and body limits by wrapping the handler with [LimitBody].
+func New(handler http.Handler, limits Limits) *http.Server {
+	maxHeaderBytes := limits.MaxHeaderBytes
+	if maxHeaderBytes == 0 {
+		maxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: limits.ReadHeaderTimeout,
+		ReadTimeout:       limits.ReadTimeout,
+		WriteTimeout:      limits.WriteTimeout,
+		IdleTimeout:       limits.IdleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+}
@@ -0,0 +1,262 @@
+package httpserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/domust/fibonacci/internal/gateway"
+)
+
+// echo responds with the request body.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.Copy(w, r.Body)
+})
+
+// serve starts a server with the given limits and returns its address.
+func serve(t *testing.T, handler http.Handler, limits Limits) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := New(LimitBody(handler, limits.MaxBodyBytes), limits)
+	go func() {
+		_ = srv.Serve(NewListener(lis, limits))
+	}()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	return lis.Addr().String()
+}
+
+// dial opens a raw connection, which fails the test if it is not closed within a few seconds.
+func dial(t *testing.T, addr string) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// closed reports whether the server closed the connection without responding.
+// Connections with unread data are reset rather than closed, so any read error counts.
+func closed(t *testing.T, conn net.Conn) bool {
+	t.Helper()
+
+	n, err := conn.Read(make([]byte, 1))
+	require.False(t, errors.Is(err, os.ErrDeadlineExceeded), "connection is still open")
+
+	return n == 0 && err != nil
+}
+
+func TestTimeouts(t *testing.T) {
+	t.Run("read header timeout", func(t *testing.T) {
+		addr := serve(t, echo, Limits{ReadHeaderTimeout: 100 * time.Millisecond})
+		conn := dial(t, addr)
+
+		// a slowloris client never finishes its headers
+		start := time.Now()
+		_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n"))
+		require.NoError(t, err)
+		require.True(t, closed(t, conn))
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("read timeout", func(t *testing.T) {
+		addr := serve(t, echo, Limits{ReadTimeout: 100 * time.Millisecond, MaxBodyBytes: 1024})
+		conn := dial(t, addr)
+
+		// the body is announced, but never sent in full
+		_, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 10\r\n\r\n12345"))
+		require.NoError(t, err)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("write timeout", func(t *testing.T) {
+		addr := serve(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte("late"))
+		}), Limits{WriteTimeout: 100 * time.Millisecond})
+		conn := dial(t, addr)
+
+		_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		require.NoError(t, err)
+		require.True(t, closed(t, conn))
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		addr := serve(t, echo, Limits{IdleTimeout: 100 * time.Millisecond})
+		conn := dial(t, addr)
+
+		_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		require.NoError(t, err)
+		r := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(r, nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+
+		start := time.Now()
+		_, err = r.ReadByte()
+		require.ErrorIs(t, err, io.EOF)
+		require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+}
+
+func TestMaxHeaderBytes(t *testing.T) {
+	addr := serve(t, echo, Limits{MaxHeaderBytes: 1024})
+
+	tests := map[string]struct {
+		size int
+		code int
+	}{
+		"within limit": {size: 512, code: http.StatusOK},
+		// the server allows some slack on top of the limit
+		"over limit": {size: 8192, code: http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			conn := dial(t, addr)
+			_, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nX-Padding: " + strings.Repeat("a", data.size) + "\r\n\r\n"))
+			require.NoError(t, err)
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			require.NoError(t, err)
+			require.Equal(t, data.code, resp.StatusCode)
+		})
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	addr := serve(t, echo, Limits{MaxBodyBytes: 16})
+
+	tests := map[string]struct {
+		request string
+		code    int
+		body    string
+	}{
+		"no body": {
+			request: "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			code:    http.StatusOK,
+		},
+		"within limit": {
+			request: "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 13\r\n\r\n{\"length\":5}\n",
+			code:    http.StatusOK,
+			body:    "{\"length\":5}\n",
+		},
+		"content length over limit": {
+			request: "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 17\r\n\r\n",
+			code:    http.StatusRequestEntityTooLarge,
+		},
+		"chunked over limit": {
+			request: "POST / HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n11\r\n" + strings.Repeat("a", 17) + "\r\n0\r\n\r\n",
+			code:    http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			conn := dial(t, addr)
+			_, err := conn.Write([]byte(data.request))
+			require.NoError(t, err)
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, data.code, resp.StatusCode)
+
+			if data.code != http.StatusOK {
+				require.Equal(t, gateway.ProblemContentType, resp.Header.Get("Content-Type"))
+				var problem gateway.Problem
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				require.Equal(t, data.code, problem.Status)
+				require.Equal(t, "request body exceeds 16 bytes", problem.Detail)
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, data.body, string(body))
+		})
+	}
+}
+
+func TestListener(t *testing.T) {
+	request := []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	t.Run("max conns", func(t *testing.T) {
+		addr := serve(t, echo, Limits{MaxConns: 1})
+		first := dial(t, addr)
+		_, err := first.Write(request)
+		require.NoError(t, err)
+		_, err = http.ReadResponse(bufio.NewReader(first), nil)
+		require.NoError(t, err)
+
+		// the second connection is queued by the kernel until the first one is closed
+		second := dial(t, addr)
+		_, err = second.Write(request)
+		require.NoError(t, err)
+		require.NoError(t, second.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+		_, err = second.Read(make([]byte, 1))
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+		require.NoError(t, first.Close())
+		require.NoError(t, second.SetReadDeadline(time.Now().Add(5*time.Second)))
+		resp, err := http.ReadResponse(bufio.NewReader(second), nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// the slot of the first connection was released once, so the third one waits for the second to be closed
+		third := dial(t, addr)
+		_, err = third.Write(request)
+		require.NoError(t, err)
+		require.NoError(t, third.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+		_, err = third.Read(make([]byte, 1))
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+		require.NoError(t, second.Close())
+		require.NoError(t, third.SetReadDeadline(time.Now().Add(5*time.Second)))
+		resp, err = http.ReadResponse(bufio.NewReader(third), nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("max conns per client", func(t *testing.T) {
+		addr := serve(t, echo, Limits{MaxConnsPerClient: 1})
+		first := dial(t, addr)
+		_, err := first.Write(request)
+		require.NoError(t, err)
+		_, err = http.ReadResponse(bufio.NewReader(first), nil)
+		require.NoError(t, err)
+
+		second := dial(t, addr)
+		require.True(t, closed(t, second))
+
+		// slots are given back once connections are closed
+		require.NoError(t, first.Close())
+		require.Eventually(t, func() bool {
+			third := dial(t, addr)
+			if _, err := third.Write(request); err != nil {
+				return false
+			}
+			resp, err := http.ReadResponse(bufio.NewReader(third), nil)
+			return err == nil && resp.StatusCode == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,130 @@
+package httpserver
+
+import (
+	"net"
+	"sync"
+)
+
+// Listener limits the number of connections accepted from the wrapped listener.
+type Listener struct {
+	net.Listener
+
+	slots     chan struct{} // nil when the total number of connections is unlimited
+	perClient int
+
+	mu      sync.Mutex
+	clients map[string]int
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewListener returns a listener enforcing the connection limits.
+func NewListener(lis net.Listener, limits Limits) *Listener {
+	l := &Listener{
+		Listener:  lis,
+		perClient: limits.MaxConnsPerClient,
+		clients:   make(map[string]int),
+		done:      make(chan struct{}),
+	}
+	if limits.MaxConns > 0 {
+		l.slots = make(chan struct{}, limits.MaxConns)
+	}
+
+	return l
+}
+
+// Accept waits for a free slot before accepting the next connection,
+// and closes connections of clients that already hold too many of them.
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		if l.slots != nil {
+			select {
+			case l.slots <- struct{}{}:
+			case <-l.done:
+				return nil, net.ErrClosed
+			}
+		}
+
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.release()
+			return nil, err
+		}
+
+		client := clientAddr(conn.RemoteAddr())
+		if !l.acquire(client) {
+			_ = conn.Close()
+			l.release()
+			continue
+		}
+
+		return &limitedConn{Conn: conn, listener: l, client: client}, nil
+	}
+}
+
+// Close closes the wrapped listener and unblocks waiting calls of Accept.
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+func (l *Listener) acquire(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perClient > 0 && l.clients[client] >= l.perClient {
+		return false
+	}
+	l.clients[client]++
+
+	return true
+}
+
+func (l *Listener) releaseClient(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.clients[client]--; l.clients[client] <= 0 {
+		delete(l.clients, client)
+	}
+}
+
+func (l *Listener) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// limitedConn gives its slot back on close.
+type limitedConn struct {
+	net.Conn
+
+	listener  *Listener
+	client    string
+	closeOnce sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		c.listener.releaseClient(c.client)
+		c.listener.release()
+	})
+
+	return err
+}
+
+// clientAddr returns the host of the address, so that connections from different ports of the same client are counted together.
+func clientAddr(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+
+	return host
+}
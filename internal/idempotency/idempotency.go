@@ -0,0 +1,388 @@
+// Package idempotency deduplicates calls retried with the same idempotency key, so that expensive calls run once.
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/domust/fibonacci/internal/caching"
+	"github.com/domust/fibonacci/internal/ratelimit"
+	"github.com/domust/fibonacci/internal/telemetry"
+)
+
+// Metadata keys of idempotency headers, which the gateway translates into the HTTP headers of the same name.
+const (
+	// KeyHeader carries the key chosen by the client, which is the same for every retry of a call.
+	KeyHeader = "idempotency-key"
+	// ReplayedHeader marks responses of calls that were not handled again, but deduplicated.
+	ReplayedHeader = "idempotent-replayed"
+)
+
+// MaxKeyLength is the maximum length of keys, which is enough for UUIDs as well as for most hashes.
+const MaxKeyLength = 255
+
+// Sources of deduplicated responses, as reported in metrics.
+const (
+	// SourceStored responses were stored after the first call completed.
+	SourceStored = "stored"
+	// SourceCoalesced responses were shared by a call completing while its duplicates were waiting for it.
+	SourceCoalesced = "coalesced"
+)
+
+// sweepInterval is how often expired outcomes are discarded.
+const sweepInterval = time.Minute
+
+// Limits bound the memory held by stored outcomes, since each of them keeps a whole response.
+type Limits struct {
+	// ClientEntries is the number of outcomes stored per client.
+	ClientEntries int
+	// ClientBytes is the size of outcomes stored per client.
+	ClientBytes int
+	// Entries is the number of outcomes stored in total.
+	Entries int
+	// Bytes is the size of outcomes stored in total.
+	Bytes int
+}
+
+// DefaultLimits are the limits applied unless configured otherwise, which keep a few of the largest pages per client.
+var DefaultLimits = Limits{
+	ClientEntries: 1 << 10,
+	ClientBytes:   16 << 20,
+	Entries:       1 << 16,
+	Bytes:         256 << 20,
+}
+
+// Clock abstracts time for testing.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Option configures the store.
+type Option func(*Store)
+
+// WithClock replaces the system clock, e.g. with a fake one in tests.
+func WithClock(clock Clock) Option {
+	return func(s *Store) {
+		s.clock = clock
+	}
+}
+
+// WithLimits replaces [DefaultLimits].
+func WithLimits(limits Limits) Option {
+	return func(s *Store) {
+		s.limits = limits
+	}
+}
+
+// outcome is the result of the first call made with a key.
+type outcome struct {
+	// fingerprint identifies the method and the request, so that keys reused for other calls are detected.
+	fingerprint string
+	resp        any
+	err         error
+	panic       *panicError
+	expires     time.Time
+	// size approximates the memory held by the outcome.
+	size int
+}
+
+// panicError carries a panic of the handler, which runs on the goroutine of the flight, over to the goroutine
+// of the first call, so that it is recovered and reported the same way as panics of calls without a key.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (p *panicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+type outcomeKey struct {
+	client string
+	key    string
+}
+
+// usage is the number and size of stored outcomes.
+type usage struct {
+	entries int
+	bytes   int
+}
+
+// Store keeps outcomes of calls per client and key.
+type Store struct {
+	ttl     time.Duration
+	clock   Clock
+	metrics *telemetry.Metrics
+	limits  Limits
+	group   singleflight.Group
+
+	mu       sync.Mutex
+	outcomes map[outcomeKey]*outcome
+	clients  map[string]*usage
+	total    usage
+	swept    time.Time
+}
+
+// New returns store keeping outcomes for the given TTL, reporting deduplicated calls to metrics.
+func New(ttl time.Duration, metrics *telemetry.Metrics, opts ...Option) *Store {
+	s := &Store{
+		ttl:      ttl,
+		clock:    systemClock{},
+		metrics:  metrics,
+		limits:   DefaultLimits,
+		outcomes: make(map[outcomeKey]*outcome),
+		clients:  make(map[string]*usage),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.swept = s.clock.Now()
+
+	return s
+}
+
+// Key returns the idempotency key of the call, which is empty when the client did not provide one.
+func Key(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(KeyHeader)
+	if len(keys) == 0 || keys[0] == "" {
+		return "", nil
+	}
+
+	if len(keys[0]) > MaxKeyLength {
+		return "", status.Errorf(codes.InvalidArgument, "%s must not be longer than %d characters", KeyHeader, MaxKeyLength)
+	}
+
+	return keys[0], nil
+}
+
+// Scope derives the keys of calls made on behalf of a single request carrying a key, e.g. calls of a JSON-RPC batch,
+// so that each of them has its own outcome. Calls without a key are left as they are.
+func Scope(ctx context.Context, scope string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(KeyHeader)
+	if len(keys) == 0 || keys[0] == "" {
+		return ctx
+	}
+
+	md = md.Copy()
+	md.Set(KeyHeader, keys[0]+"/"+scope)
+
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// UnaryInterceptor handles the first call made with a key, while its duplicates wait for it to complete
+// and receive its response too. Responses of calls that may succeed when retried, e.g. calls that timed out,
+// are not stored, so that they are handled again, and neither are outcomes exceeding the limits of the store.
+// Keys reused for calls of other methods or other requests are rejected with FailedPrecondition.
+func (s *Store) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key, err := Key(ctx)
+		if err != nil {
+			return nil, err
+		}
+		msg, ok := req.(proto.Message)
+		if key == "" || !ok {
+			return handler(ctx, req)
+		}
+
+		fingerprint, err := caching.ETag(info.FullMethod, msg)
+		if err != nil {
+			return handler(ctx, req)
+		}
+
+		// keys are scoped to the authenticated principal, or to the address of anonymous clients, but never to
+		// unverified credentials, which would let clients read outcomes of one another by guessing their keys
+		k := outcomeKey{client: ratelimit.Key(ctx), key: key}
+		for {
+			if o, ok := s.load(k); ok {
+				return s.replay(ctx, info.FullMethod, o, fingerprint, SourceStored)
+			}
+
+			var first bool
+			ch := s.group.DoChan(k.client+"\x00"+k.key, func() (any, error) {
+				// the outcome may have been stored right after it was looked up
+				if o, ok := s.load(k); ok {
+					return o, nil
+				}
+
+				first = true
+				o := s.handle(ctx, req, handler, fingerprint)
+				if !transient(o.err) {
+					s.store(k, o)
+				}
+				return o, nil
+			})
+
+			select {
+			case <-ctx.Done():
+				return nil, status.FromContextError(ctx.Err()).Err()
+			case res := <-ch:
+				o := res.Val.(*outcome)
+				if first {
+					if o.panic != nil {
+						panic(o.panic)
+					}
+					return o.resp, o.err
+				}
+				if transient(o.err) && o.fingerprint == fingerprint {
+					// the first call failed for reasons of its own, e.g. because its client gave up on it
+					continue
+				}
+				return s.replay(ctx, info.FullMethod, o, fingerprint, SourceCoalesced)
+			}
+		}
+	}
+}
+
+// StreamInterceptor leaves streams as they are, since their responses are sent before the outcome is known.
+func (s *Store) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, ss)
+	}
+}
+
+// handle calls the handler, recovering from panics, which would otherwise crash the process.
+func (s *Store) handle(ctx context.Context, req any, handler grpc.UnaryHandler, fingerprint string) (o *outcome) {
+	o = &outcome{fingerprint: fingerprint, expires: s.clock.Now().Add(s.ttl)}
+	defer func() {
+		if p := recover(); p != nil {
+			o.panic = &panicError{value: p, stack: debug.Stack()}
+			o.resp, o.err = nil, status.Error(codes.Internal, "panic")
+		}
+	}()
+
+	o.resp, o.err = handler(ctx, req)
+
+	return o
+}
+
+// replay returns a copy of the stored response, unless the key was used for another call.
+func (s *Store) replay(ctx context.Context, method string, o *outcome, fingerprint, source string) (any, error) {
+	if o.fingerprint != fingerprint {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s was already used for a different request", KeyHeader)
+	}
+
+	s.metrics.Deduplicated(ctx, method, source)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(ReplayedHeader, "true"))
+
+	if msg, ok := o.resp.(proto.Message); ok && o.err == nil {
+		return proto.Clone(msg), nil
+	}
+
+	return o.resp, o.err
+}
+
+func (s *Store) load(k outcomeKey) (*outcome, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if now.Sub(s.swept) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	o, ok := s.outcomes[k]
+	if !ok || !now.Before(o.expires) {
+		return nil, false
+	}
+
+	return o, true
+}
+
+// store keeps the outcome, unless it would exceed the limits, in which case retries are handled again.
+// Outcomes are not evicted to make room, so that clients cannot discard outcomes of one another.
+func (s *Store) store(k outcomeKey, o *outcome) {
+	o.size = len(k.client) + len(k.key) + len(o.fingerprint)
+	if msg, ok := o.resp.(proto.Message); ok {
+		o.size += proto.Size(msg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.outcomes[k]; ok {
+		s.remove(k, old)
+	}
+
+	if !s.fits(k.client, o.size) {
+		s.sweep(s.clock.Now())
+		if !s.fits(k.client, o.size) {
+			return
+		}
+	}
+
+	u, ok := s.clients[k.client]
+	if !ok {
+		u = &usage{}
+		s.clients[k.client] = u
+	}
+	u.entries++
+	u.bytes += o.size
+	s.total.entries++
+	s.total.bytes += o.size
+	s.outcomes[k] = o
+}
+
+// fits reports whether an outcome of the given size can be stored for the client within the limits.
+func (s *Store) fits(client string, size int) bool {
+	var u usage
+	if c, ok := s.clients[client]; ok {
+		u = *c
+	}
+
+	return u.entries < s.limits.ClientEntries && u.bytes+size <= s.limits.ClientBytes &&
+		s.total.entries < s.limits.Entries && s.total.bytes+size <= s.limits.Bytes
+}
+
+// remove discards the outcome, releasing its share of the limits.
+func (s *Store) remove(k outcomeKey, o *outcome) {
+	delete(s.outcomes, k)
+
+	s.total.entries--
+	s.total.bytes -= o.size
+	if u, ok := s.clients[k.client]; ok {
+		u.entries--
+		u.bytes -= o.size
+		if u.entries == 0 {
+			delete(s.clients, k.client)
+		}
+	}
+}
+
+// sweep discards expired outcomes.
+func (s *Store) sweep(now time.Time) {
+	for k, o := range s.outcomes {
+		if !now.Before(o.expires) {
+			s.remove(k, o)
+		}
+	}
+	s.swept = now
+}
+
+// transient reports whether the call failed for reasons that may not apply to its retries.
+func transient(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Unavailable,
+		codes.Internal, codes.Unknown:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,359 @@
+package idempotency
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal/auth"
+)
+
+const method = "/api.v1.Fibonacci/GenerateSequence"
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// withKey returns the context of a call carrying the idempotency key.
+func withKey(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(KeyHeader, key))
+}
+
+// counting returns a handler responding with the sequence of the requested length, which counts its calls.
+func counting(calls *atomic.Int32) grpc.UnaryHandler {
+	return func(_ context.Context, req any) (any, error) {
+		calls.Add(1)
+		return &api.GenerateSequenceResponse{Sequence: make([]uint64, req.(*api.GenerateSequenceRequest).GetLength())}, nil
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	req := &api.GenerateSequenceRequest{Length: 5}
+
+	t.Run("repeats are replayed", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+
+		first, err := interceptor(withKey("a"), req, info, counting(&calls))
+		require.NoError(t, err)
+		second, err := interceptor(withKey("a"), req, info, counting(&calls))
+		require.NoError(t, err)
+
+		require.Equal(t, int32(1), calls.Load())
+		require.Len(t, second.(*api.GenerateSequenceResponse).GetSequence(), 5)
+		require.NotSame(t, first, second)
+	})
+
+	t.Run("calls without keys", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+
+		for range 2 {
+			_, err := interceptor(context.Background(), req, info, counting(&calls))
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("keys are per client", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+
+		for _, client := range []string{"alice", "bob"} {
+			ctx := auth.NewContext(withKey("a"), &auth.Principal{Name: client, Method: "jwt"})
+			_, err := interceptor(ctx, req, info, counting(&calls))
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("keys are per principal rather than per address", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+		addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+
+		for _, client := range []string{"alice", "bob", "alice"} {
+			ctx := peer.NewContext(withKey("a"), &peer.Peer{Addr: addr})
+			ctx = auth.NewContext(ctx, &auth.Principal{Name: client, Method: "jwt"})
+			_, err := interceptor(ctx, req, info, counting(&calls))
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(2), calls.Load())
+
+		// unverified api keys do not tell anonymous clients apart
+		for _, apiKey := range []string{"x", "y"} {
+			md := metadata.Pairs(KeyHeader, "b", auth.APIKeyHeader, apiKey)
+			ctx := peer.NewContext(metadata.NewIncomingContext(context.Background(), md), &peer.Peer{Addr: addr})
+			_, err := interceptor(ctx, req, info, counting(&calls))
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("outcomes expire", func(t *testing.T) {
+		var calls atomic.Int32
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		interceptor := New(time.Hour, nil, WithClock(clock)).UnaryInterceptor()
+
+		_, err := interceptor(withKey("a"), req, info, counting(&calls))
+		require.NoError(t, err)
+		clock.Advance(time.Hour)
+		_, err = interceptor(withKey("a"), req, info, counting(&calls))
+		require.NoError(t, err)
+
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("outcomes exceeding the limits are not stored", func(t *testing.T) {
+		tests := map[string]struct {
+			limits Limits
+			client string
+			req    *api.GenerateSequenceRequest
+			calls  int32
+		}{
+			"within limits": {
+				limits: Limits{ClientEntries: 2, ClientBytes: 1 << 10, Entries: 2, Bytes: 1 << 10},
+				client: "alice",
+				req:    req,
+				calls:  1,
+			},
+			"client entries": {
+				limits: Limits{ClientEntries: 1, ClientBytes: 1 << 10, Entries: 2, Bytes: 1 << 10},
+				client: "alice",
+				req:    req,
+				calls:  2,
+			},
+			"client bytes": {
+				limits: Limits{ClientEntries: 2, ClientBytes: 1 << 10, Entries: 2, Bytes: 1 << 20},
+				client: "alice",
+				req:    &api.GenerateSequenceRequest{Length: 1000},
+				calls:  2,
+			},
+			"total entries": {
+				limits: Limits{ClientEntries: 2, ClientBytes: 1 << 10, Entries: 1, Bytes: 1 << 10},
+				client: "bob",
+				req:    req,
+				calls:  2,
+			},
+			"total bytes": {
+				limits: Limits{ClientEntries: 2, ClientBytes: 1 << 20, Entries: 2, Bytes: 1 << 10},
+				client: "bob",
+				req:    &api.GenerateSequenceRequest{Length: 1000},
+				calls:  2,
+			},
+		}
+
+		for name, data := range tests {
+			t.Run(name, func(t *testing.T) {
+				var calls atomic.Int32
+				interceptor := New(time.Hour, nil, WithLimits(data.limits)).UnaryInterceptor()
+				alice := auth.NewContext(withKey("a"), &auth.Principal{Name: "alice", Method: "jwt"})
+				ctx := auth.NewContext(withKey("b"), &auth.Principal{Name: data.client, Method: "jwt"})
+
+				_, err := interceptor(alice, req, info, counting(&calls))
+				require.NoError(t, err)
+				for range 2 {
+					_, err = interceptor(ctx, data.req, info, counting(&calls))
+					require.NoError(t, err)
+				}
+
+				require.Equal(t, data.calls+1, calls.Load())
+			})
+		}
+	})
+
+	t.Run("expired outcomes make room", func(t *testing.T) {
+		var calls atomic.Int32
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		limits := Limits{ClientEntries: 1, ClientBytes: 1 << 10, Entries: 1, Bytes: 1 << 10}
+		interceptor := New(time.Hour, nil, WithClock(clock), WithLimits(limits)).UnaryInterceptor()
+
+		_, err := interceptor(withKey("a"), req, info, counting(&calls))
+		require.NoError(t, err)
+		clock.Advance(time.Hour)
+		for range 2 {
+			_, err = interceptor(withKey("b"), req, info, counting(&calls))
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("errors are replayed", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+		handler := func(context.Context, any) (any, error) {
+			calls.Add(1)
+			return nil, status.Error(codes.OutOfRange, "too long")
+		}
+
+		for range 2 {
+			_, err := interceptor(withKey("a"), req, info, handler)
+			require.Equal(t, codes.OutOfRange.String(), status.Code(err).String())
+		}
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("transient errors are not stored", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+		handler := func(context.Context, any) (any, error) {
+			if calls.Add(1) == 1 {
+				return nil, status.Error(codes.DeadlineExceeded, "too slow")
+			}
+			return &api.GenerateSequenceResponse{}, nil
+		}
+
+		_, err := interceptor(withKey("a"), req, info, handler)
+		require.Equal(t, codes.DeadlineExceeded.String(), status.Code(err).String())
+		_, err = interceptor(withKey("a"), req, info, handler)
+		require.NoError(t, err)
+
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("keys reused for different requests", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+
+		_, err := interceptor(withKey("a"), req, info, counting(&calls))
+		require.NoError(t, err)
+		_, err = interceptor(withKey("a"), &api.GenerateSequenceRequest{Length: 6}, info, counting(&calls))
+		require.Equal(t, codes.FailedPrecondition.String(), status.Code(err).String())
+		_, err = interceptor(withKey("a"), &api.GetNumberRequest{Index: 5}, &grpc.UnaryServerInfo{FullMethod: "/api.v1.Fibonacci/GetNumber"}, counting(&calls))
+		require.Equal(t, codes.FailedPrecondition.String(), status.Code(err).String())
+
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("keys that are too long", func(t *testing.T) {
+		var calls atomic.Int32
+		_, err := New(time.Hour, nil).UnaryInterceptor()(withKey(strings.Repeat("a", MaxKeyLength+1)), req, info, counting(&calls))
+		require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+		require.Zero(t, calls.Load())
+	})
+
+	t.Run("concurrent duplicates are coalesced", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+		release := make(chan struct{})
+		handler := func(ctx context.Context, req any) (any, error) {
+			<-release
+			return counting(&calls)(ctx, req)
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := interceptor(withKey("a"), req, info, handler)
+				errs <- err
+			}()
+		}
+		// give duplicates a chance to join the flight of the first call before it completes
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("duplicates outlive canceled calls", func(t *testing.T) {
+		var calls atomic.Int32
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+		started := make(chan struct{})
+		handler := func(ctx context.Context, req any) (any, error) {
+			if calls.Add(1) == 1 {
+				close(started)
+				<-ctx.Done()
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+			return &api.GenerateSequenceResponse{}, nil
+		}
+
+		ctx, cancel := context.WithCancel(withKey("a"))
+		done := make(chan error)
+		go func() {
+			_, err := interceptor(ctx, req, info, handler)
+			done <- err
+		}()
+		<-started
+
+		duplicate := make(chan error)
+		go func() {
+			_, err := interceptor(withKey("a"), req, info, handler)
+			duplicate <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		require.Equal(t, codes.Canceled.String(), status.Code(<-done).String())
+		require.NoError(t, <-duplicate)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("panics are carried over to the first call", func(t *testing.T) {
+		interceptor := New(time.Hour, nil).UnaryInterceptor()
+		handler := func(context.Context, any) (any, error) {
+			panic("boom")
+		}
+
+		func() {
+			defer func() {
+				p, ok := recover().(*panicError)
+				require.True(t, ok)
+				require.Equal(t, "boom", p.value)
+				require.Contains(t, string(p.stack), "idempotency_test.go", "the stack is the one of the handler")
+			}()
+
+			_, _ = interceptor(withKey("a"), req, info, handler)
+		}()
+	})
+}
+
+func TestScope(t *testing.T) {
+	ctx := Scope(withKey("a"), "1")
+	key, err := Key(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a/1", key)
+
+	key, err = Key(withKey("a"))
+	require.NoError(t, err)
+	require.Equal(t, "a", key, "the original metadata is left as it is")
+
+	key, err = Key(Scope(context.Background(), "1"))
+	require.NoError(t, err)
+	require.Empty(t, key)
+}
@@ -0,0 +1,286 @@
+// Package jsonrpc serves the Fibonacci API over JSON-RPC 2.0 for consumers that speak nothing else.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal/gateway"
+	rpc "github.com/domust/fibonacci/internal/grpc"
+	"github.com/domust/fibonacci/internal/idempotency"
+)
+
+// Path is the path of the JSON-RPC endpoint.
+const Path = "/jsonrpc"
+
+// Version is the only supported version of the protocol.
+const Version = "2.0"
+
+// Namespace prefixes method names, e.g. "fibonacci.generateSequence" dispatches to GenerateSequence.
+const Namespace = "fibonacci"
+
+// Error codes defined by the specification.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+	// ServerError is the upper bound of codes reserved for implementation-defined server errors,
+	// from which grpc codes without a counterpart in the specification are subtracted, e.g. -32016 for UNAUTHENTICATED.
+	ServerError = -32000
+)
+
+// Request is a single call, which is a notification when it has no ID.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// Response carries either the result or the error of a call.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error describes a failed call.
+type Error struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    *ErrorData `json:"data,omitempty"`
+}
+
+// ErrorData carries the grpc status of a failed call along with invalid fields of the request.
+type ErrorData struct {
+	Status     string              `json:"status"`
+	Violations []gateway.Violation `json:"violations,omitempty"`
+}
+
+// null is the ID of responses to requests whose ID could not be determined.
+var null = json.RawMessage("null")
+
+// method dispatches a call to the server through the interceptors.
+type method struct {
+	fullMethod string
+	call       func(ctx context.Context, params json.RawMessage) (proto.Message, error)
+}
+
+// Register serves JSON-RPC calls of the server's unary methods, which are dispatched through the interceptors
+// the same way as grpc calls, e.g. in order to validate requests. Request headers are passed to the interceptors
+// as incoming metadata, the same way as headers of proxied calls, so that callers can be authenticated.
+func Register(mux *runtime.ServeMux, srv api.FibonacciServer, interceptors ...grpc.UnaryServerInterceptor) error {
+	h := &handler{mux: mux, methods: make(map[string]method)}
+	interceptor := rpc.ChainUnaryInterceptors(interceptors...)
+	for _, desc := range api.Fibonacci_ServiceDesc.Methods {
+		name := Namespace + "." + lowerFirst(desc.MethodName)
+		h.methods[name] = method{
+			fullMethod: "/" + api.Fibonacci_ServiceDesc.ServiceName + "/" + desc.MethodName,
+			call: func(ctx context.Context, params json.RawMessage) (proto.Message, error) {
+				resp, err := desc.Handler(srv, ctx, decoder(params), interceptor)
+				if err != nil {
+					return nil, err
+				}
+				return resp.(proto.Message), nil
+			},
+		}
+	}
+
+	if err := mux.HandlePath(http.MethodPost, Path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		h.ServeHTTP(w, r)
+	}); err != nil {
+		return fmt.Errorf("jsonrpc %s: %w", Path, err)
+	}
+
+	return nil
+}
+
+type handler struct {
+	mux     *runtime.ServeMux
+	methods map[string]method
+}
+
+// ServeHTTP responds to a single request or a batch of them, omitting responses to notifications.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, failure(null, ParseError, fmt.Sprintf("reading request: %v", err)))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		if resp := h.call(r, body, ""); resp != nil {
+			writeJSON(w, resp)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		writeJSON(w, failure(null, ParseError, err.Error()))
+		return
+	}
+	if len(batch) == 0 {
+		writeJSON(w, failure(null, InvalidRequest, "batch must not be empty"))
+		return
+	}
+
+	var responses []*Response
+	for i, msg := range batch {
+		if resp := h.call(r, msg, strconv.Itoa(i)); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, responses)
+}
+
+// call handles a single request, returning nil for notifications.
+// Calls of a batch are scoped by their position, so that each of them is deduplicated on its own when retried.
+func (h *handler) call(r *http.Request, msg json.RawMessage, scope string) *Response {
+	var req Request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return failure(null, ParseError, err.Error())
+		}
+		return failure(null, InvalidRequest, err.Error())
+	}
+
+	id := req.ID
+	if id == nil {
+		id = null
+	}
+	if req.JSONRPC != Version || req.Method == "" || !validID(req.ID) {
+		return failure(id, InvalidRequest, fmt.Sprintf("request must have jsonrpc %q, a method and a string, number or null id", Version))
+	}
+
+	m, ok := h.methods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return failure(id, MethodNotFound, fmt.Sprintf("method %q not found", req.Method))
+	}
+
+	ctx, err := gateway.IncomingContext(h.mux, r, m.fullMethod, Path)
+	if err != nil {
+		if req.ID == nil {
+			return nil
+		}
+		return &Response{JSONRPC: Version, Error: NewError(status.Convert(err)), ID: id}
+	}
+	if scope != "" {
+		ctx = idempotency.Scope(ctx, scope)
+	}
+
+	resp, err := m.call(ctx, req.Params)
+	if req.ID == nil {
+		return nil // notifications are never answered, not even with errors
+	}
+	if err != nil {
+		return &Response{JSONRPC: Version, Error: NewError(status.Convert(err)), ID: id}
+	}
+
+	result, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(resp)
+	if err != nil {
+		return failure(id, InternalError, err.Error())
+	}
+
+	return &Response{JSONRPC: Version, Result: result, ID: id}
+}
+
+// NewError converts the grpc status of a failed call into a JSON-RPC error.
+func NewError(s *status.Status) *Error {
+	p := gateway.NewProblem(s, "")
+	return &Error{
+		Code:    ErrorCode(s.Code()),
+		Message: s.Message(),
+		Data:    &ErrorData{Status: p.Code, Violations: p.Violations},
+	}
+}
+
+// ErrorCode maps grpc codes to the codes defined by the specification where they exist,
+// and to implementation-defined server errors otherwise.
+func ErrorCode(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return InvalidParams
+	case codes.Unimplemented:
+		return MethodNotFound
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		return InternalError
+	}
+
+	return ServerError - int(code)
+}
+
+// decoder decodes params into requests, treating missing params as an empty request.
+func decoder(params json.RawMessage) func(any) error {
+	return func(v any) error {
+		if len(params) == 0 || bytes.Equal(params, null) {
+			return nil
+		}
+		if params[0] != '{' {
+			return status.Error(codes.InvalidArgument, "params must be an object")
+		}
+		if err := protojson.Unmarshal(params, v.(proto.Message)); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid params: %v", err)
+		}
+		return nil
+	}
+}
+
+func failure(id json.RawMessage, code int, message string) *Response {
+	return &Response{JSONRPC: Version, Error: &Error{Code: code, Message: message}, ID: id}
+}
+
+// validID reports whether the ID is absent, a string, a number or null.
+func validID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+
+	switch c := id[0]; {
+	case c == '"', c == 'n', c == '-', c >= '0' && c <= '9':
+		return true
+	}
+
+	return false
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
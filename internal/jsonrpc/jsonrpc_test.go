@@ -0,0 +1,244 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"buf.build/go/protovalidate"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal"
+	"github.com/domust/fibonacci/internal/auth"
+	"github.com/domust/fibonacci/internal/gateway"
+	"github.com/domust/fibonacci/internal/idempotency"
+	"github.com/domust/fibonacci/internal/validation"
+)
+
+func TestRegister(t *testing.T) {
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+
+	var methods []string
+	record := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		methods = append(methods, info.FullMethod)
+		return handler(ctx, req)
+	}
+
+	mux := runtime.NewServeMux()
+	require.NoError(t, Register(mux, internal.NewServer(nil), record, validation.New(validator).UnaryInterceptor()))
+
+	tests := map[string]struct {
+		request  string
+		code     int
+		response string
+		methods  []string
+	}{
+		"call": {
+			request:  `{"jsonrpc":"2.0","method":"fibonacci.generateSequence","params":{"length":5},"id":1}`,
+			code:     http.StatusOK,
+			response: `{"jsonrpc":"2.0","result":{"sequence":["0","1","1","2","3"]},"id":1}`,
+			methods:  []string{api.Fibonacci_GenerateSequence_FullMethodName},
+		},
+		"string id": {
+			request:  `{"jsonrpc":"2.0","method":"fibonacci.getNumber","params":{"index":9},"id":"abc"}`,
+			code:     http.StatusOK,
+			response: `{"jsonrpc":"2.0","result":{"number":"34"},"id":"abc"}`,
+			methods:  []string{api.Fibonacci_GetNumber_FullMethodName},
+		},
+		"null id": {
+			request:  `{"jsonrpc":"2.0","method":"fibonacci.getNumber","id":null}`,
+			code:     http.StatusOK,
+			response: `{"jsonrpc":"2.0","result":{"number":"0"},"id":null}`,
+			methods:  []string{api.Fibonacci_GetNumber_FullMethodName},
+		},
+		"notification": {
+			request: `{"jsonrpc":"2.0","method":"fibonacci.generateSequence","params":{"length":5}}`,
+			code:    http.StatusNoContent,
+			methods: []string{api.Fibonacci_GenerateSequence_FullMethodName},
+		},
+		"failed notification": {
+			request: `{"jsonrpc":"2.0","method":"fibonacci.generateSequence","params":{"length":95}}`,
+			code:    http.StatusNoContent,
+			methods: []string{api.Fibonacci_GenerateSequence_FullMethodName},
+		},
+		"validation": {
+			request: `{"jsonrpc":"2.0","method":"fibonacci.generateSequence","params":{"length":95},"id":1}`,
+			code:    http.StatusOK,
+			response: `{"jsonrpc":"2.0","error":{"code":-32602,
+				"message":"invalid GenerateSequenceRequest: length: value must be greater than 0 and less than 95",
+				"data":{"status":"INVALID_ARGUMENT","violations":[{"field":"length","rule":"uint32.gt_lt","message":"value must be greater than 0 and less than 95"}]}},
+				"id":1}`,
+			methods: []string{api.Fibonacci_GenerateSequence_FullMethodName},
+		},
+		"positional params": {
+			request:  `{"jsonrpc":"2.0","method":"fibonacci.generateSequence","params":[5],"id":1}`,
+			code:     http.StatusOK,
+			response: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"params must be an object","data":{"status":"INVALID_ARGUMENT"}},"id":1}`,
+		},
+		"unknown method": {
+			request:  `{"jsonrpc":"2.0","method":"fibonacci.streamSequence","id":1}`,
+			code:     http.StatusOK,
+			response: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method \"fibonacci.streamSequence\" not found"},"id":1}`,
+		},
+		"invalid version": {
+			request:  `{"jsonrpc":"1.0","method":"fibonacci.getNumber","id":1}`,
+			code:     http.StatusOK,
+			response: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"request must have jsonrpc \"2.0\", a method and a string, number or null id"},"id":1}`,
+		},
+		"parse error": {
+			request:  `{"jsonrpc":"2.0","method"`,
+			code:     http.StatusOK,
+			response: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"unexpected end of JSON input"},"id":null}`,
+		},
+		"batch": {
+			request: `[
+				{"jsonrpc":"2.0","method":"fibonacci.getNumber","params":{"index":3},"id":1},
+				{"jsonrpc":"2.0","method":"fibonacci.getNumber","params":{"index":4}},
+				{"jsonrpc":"2.0","method":"fibonacci.getNumber","params":{"index":94},"id":2},
+				1
+			]`,
+			code: http.StatusOK,
+			response: `[
+				{"jsonrpc":"2.0","result":{"number":"2"},"id":1},
+				{"jsonrpc":"2.0","error":{"code":-32602,
+					"message":"invalid GetNumberRequest: index: value must be less than 94",
+					"data":{"status":"INVALID_ARGUMENT","violations":[{"field":"index","rule":"uint32.lt","message":"value must be less than 94"}]}},
+					"id":2},
+				{"jsonrpc":"2.0","error":{"code":-32600,"message":"json: cannot unmarshal number into Go value of type jsonrpc.Request"},"id":null}
+			]`,
+			methods: []string{api.Fibonacci_GetNumber_FullMethodName, api.Fibonacci_GetNumber_FullMethodName, api.Fibonacci_GetNumber_FullMethodName},
+		},
+		"batch of notifications": {
+			request: `[{"jsonrpc":"2.0","method":"fibonacci.getNumber"}]`,
+			code:    http.StatusNoContent,
+			methods: []string{api.Fibonacci_GetNumber_FullMethodName},
+		},
+		"empty batch": {
+			request:  `[]`,
+			code:     http.StatusOK,
+			response: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"batch must not be empty"},"id":null}`,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			methods = nil
+
+			req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(data.request))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			require.Equal(t, data.code, rec.Code)
+			require.Equal(t, data.methods, methods)
+			if data.response == "" {
+				require.Empty(t, rec.Body.String())
+				return
+			}
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			require.JSONEq(t, data.response, rec.Body.String())
+		})
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(`{"jsonrpc":"2.0","method":"fibonacci.generateSequence","params":{"size":5},"id":1}`))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		var resp Response
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, InvalidParams, resp.Error.Code)
+		// protojson randomizes whitespace of its errors, so only their gist is checked
+		require.Contains(t, resp.Error.Message, `unknown field "size"`)
+	})
+}
+
+func TestRegisterMetadata(t *testing.T) {
+	var (
+		md metadata.MD
+		p  *peer.Peer
+	)
+	capture := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ = metadata.FromIncomingContext(ctx)
+		p, _ = peer.FromContext(ctx)
+		return handler(ctx, req)
+	}
+
+	mux := gateway.NewServeMux()
+	require.NoError(t, Register(mux, internal.NewServer(nil), capture))
+
+	req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(`{"jsonrpc":"2.0","method":"fibonacci.getNumber","id":1}`))
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Api-Key", "secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	// headers are passed to interceptors the same way as headers of calls proxied by the gateway
+	require.Equal(t, []string{"secret"}, md.Get(auth.APIKeyHeader))
+	require.Equal(t, "192.0.2.1:1234", p.Addr.String())
+}
+
+func TestRegisterIdempotency(t *testing.T) {
+	var calls int
+	count := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		calls++
+		return handler(ctx, req)
+	}
+
+	mux := gateway.NewServeMux()
+	require.NoError(t, Register(mux, internal.NewServer(nil), idempotency.New(time.Hour, nil).UnaryInterceptor(), count))
+
+	post := func(body string) string {
+		req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "a")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	// calls of a batch share the key of the request, yet each of them has its own outcome
+	batch := `[{"jsonrpc":"2.0","method":"fibonacci.getNumber","params":{"index":3},"id":1},
+		{"jsonrpc":"2.0","method":"fibonacci.generateSequence","params":{"length":3},"id":2}]`
+	first := post(batch)
+	require.JSONEq(t, `[{"jsonrpc":"2.0","result":{"number":"2"},"id":1},
+		{"jsonrpc":"2.0","result":{"sequence":["0","1","1"]},"id":2}]`, first)
+	require.Equal(t, 2, calls)
+
+	require.JSONEq(t, first, post(batch))
+	require.Equal(t, 2, calls, "retried batch is replayed")
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := map[codes.Code]int{
+		codes.InvalidArgument:   InvalidParams,
+		codes.OutOfRange:        InvalidParams,
+		codes.Unimplemented:     MethodNotFound,
+		codes.Internal:          InternalError,
+		codes.Unknown:           InternalError,
+		codes.Canceled:          -32001,
+		codes.DeadlineExceeded:  -32004,
+		codes.PermissionDenied:  -32007,
+		codes.ResourceExhausted: -32008,
+		codes.Unavailable:       -32014,
+		codes.Unauthenticated:   -32016,
+	}
+
+	for code, expected := range tests {
+		t.Run(code.String(), func(t *testing.T) {
+			require.Equal(t, expected, ErrorCode(code))
+		})
+	}
+}
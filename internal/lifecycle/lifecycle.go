@@ -0,0 +1,103 @@
+// Package lifecycle coordinates startup and shutdown of long running components.
+package lifecycle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+type actor struct {
+	execute   func() error
+	interrupt func(error)
+}
+
+// Group runs actors concurrently until the first one returns, after which all actors are interrupted.
+type Group struct {
+	actors []actor
+}
+
+// Add registers an actor. The execute function must block until the actor stops, and the interrupt function
+// must cause execute to return. Interrupts are invoked sequentially in reverse order of registration,
+// so actors that others depend upon should be added first.
+func (g *Group) Add(execute func() error, interrupt func(error)) {
+	g.actors = append(g.actors, actor{execute: execute, interrupt: interrupt})
+}
+
+// Run starts all actors and blocks until every one of them has returned.
+// The error of the first actor to return is the error of the group.
+func (g *Group) Run() error {
+	if len(g.actors) == 0 {
+		return nil
+	}
+
+	errs := make(chan error, len(g.actors))
+	for _, a := range g.actors {
+		go func() {
+			errs <- a.execute()
+		}()
+	}
+
+	err := <-errs
+	for i := len(g.actors) - 1; i >= 0; i-- {
+		g.actors[i].interrupt(err)
+	}
+	for range len(g.actors) - 1 {
+		<-errs
+	}
+
+	return err
+}
+
+// Signal returns an actor that returns once ctx is done, typically due to a termination signal.
+// Before returning, it calls drain and then waits for the given period, so that load balancers
+// have time to stop sending new traffic before listeners are closed.
+func Signal(ctx context.Context, period time.Duration, drain func()) (func() error, func(error)) {
+	stop := make(chan struct{})
+	var once sync.Once
+
+	execute := func() error {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+			return nil
+		}
+
+		drain()
+
+		timer := time.NewTimer(period)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-stop:
+		}
+
+		return nil
+	}
+
+	interrupt := func(error) {
+		once.Do(func() { close(stop) })
+	}
+
+	return execute, interrupt
+}
+
+// GracefulStop stops the grpc server gracefully, forcing it to stop once timeout is exceeded.
+func GracefulStop(s *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		s.Stop()
+		<-done
+	}
+}
@@ -0,0 +1,80 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var g Group
+		require.NoError(t, g.Run())
+	})
+
+	t.Run("first error wins", func(t *testing.T) {
+		failure := errors.New("failure")
+
+		var g Group
+		g.Add(func() error { return failure }, func(error) {})
+
+		stop := make(chan struct{})
+		var interrupted error
+		g.Add(func() error {
+			<-stop
+			return errors.New("interrupted")
+		}, func(err error) {
+			interrupted = err
+			close(stop)
+		})
+
+		require.ErrorIs(t, g.Run(), failure)
+		require.ErrorIs(t, interrupted, failure)
+	})
+
+	t.Run("interrupts in reverse order", func(t *testing.T) {
+		var g Group
+		var order []int
+
+		g.Add(func() error { return nil }, func(error) { order = append(order, 0) })
+		for i := 1; i <= 2; i++ {
+			stop := make(chan struct{})
+			g.Add(func() error {
+				<-stop
+				return nil
+			}, func(error) {
+				order = append(order, i)
+				close(stop)
+			})
+		}
+
+		require.NoError(t, g.Run())
+		require.Equal(t, []int{2, 1, 0}, order)
+	})
+}
+
+func TestSignal(t *testing.T) {
+	t.Run("drains before returning", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		drained := false
+		execute, _ := Signal(ctx, 10*time.Millisecond, func() { drained = true })
+
+		cancel()
+		start := time.Now()
+		require.NoError(t, execute())
+		require.True(t, drained)
+		require.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	})
+
+	t.Run("interrupt skips drain", func(t *testing.T) {
+		drained := false
+		execute, interrupt := Signal(context.Background(), time.Hour, func() { drained = true })
+
+		interrupt(nil)
+		require.NoError(t, execute())
+		require.False(t, drained)
+	})
+}
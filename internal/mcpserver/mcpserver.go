@@ -0,0 +1,153 @@
+// Package mcpserver exposes the Fibonacci API as Model Context Protocol tools for AI assistants.
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal/gateway"
+	rpc "github.com/domust/fibonacci/internal/grpc"
+)
+
+// Path is the path of the streamable HTTP endpoint.
+const Path = "/mcp"
+
+// implementation identifies the server to clients.
+var implementation = &mcp.Implementation{Name: "fibonacci", Title: "Fibonacci", Version: "v1"}
+
+// descriptions tell assistants what tools are for, since comments of the protos are not available at runtime.
+var descriptions = map[string]string{
+	"GenerateSequence": "Generates the first length terms of the Fibonacci sequence, starting with 0 and 1.",
+	"GetNumber":        "Returns the term of the Fibonacci sequence at the given zero-based index.",
+}
+
+// New returns a server with a tool per unary method of the Fibonacci API, such as generate_sequence,
+// whose input and output schemas are derived from the request and response messages.
+// Calls are dispatched through the interceptors the same way as grpc calls, e.g. in order to validate arguments,
+// and failed calls are reported to assistants as tool errors carrying problem details.
+func New(srv api.FibonacciServer, interceptors ...grpc.UnaryServerInterceptor) (*mcp.Server, error) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(api.Fibonacci_ServiceDesc.ServiceName))
+	if err != nil {
+		return nil, fmt.Errorf("mcp: %w", err)
+	}
+	service := d.(protoreflect.ServiceDescriptor)
+
+	s := mcp.NewServer(implementation, nil)
+	interceptor := rpc.ChainUnaryInterceptors(interceptors...)
+	for _, desc := range api.Fibonacci_ServiceDesc.Methods {
+		method := service.Methods().ByName(protoreflect.Name(desc.MethodName))
+		if method == nil {
+			return nil, fmt.Errorf("mcp: descriptor of %s not found", desc.MethodName)
+		}
+
+		description, ok := descriptions[desc.MethodName]
+		if !ok {
+			description = desc.MethodName
+		}
+
+		s.AddTool(&mcp.Tool{
+			Name:         ToolName(desc.MethodName),
+			Description:  description,
+			InputSchema:  Schema(method.Input()),
+			OutputSchema: Schema(method.Output()),
+			// sequences are computed rather than looked up, so calls never have side effects
+			Annotations: &mcp.ToolAnnotations{ReadOnlyHint: true, IdempotentHint: true, OpenWorldHint: new(bool)},
+		}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			resp, err := desc.Handler(srv, ctx, decoder(req.Params.Arguments), interceptor)
+			if err != nil {
+				return failure(status.Convert(err)), nil
+			}
+
+			data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(resp.(proto.Message))
+			if err != nil {
+				return failure(status.Convert(err)), nil
+			}
+
+			return &mcp.CallToolResult{
+				// structured content is accompanied by the same JSON as text for clients predating it
+				Content:           []mcp.Content{&mcp.TextContent{Text: string(data)}},
+				StructuredContent: json.RawMessage(data),
+			}, nil
+		})
+	}
+
+	return s, nil
+}
+
+// Register serves the server over streamable HTTP. Sessions are stateless, so that any replica can serve any call,
+// and request headers are passed to the interceptors as incoming metadata, so that callers can be authenticated.
+func Register(mux *runtime.ServeMux, s *mcp.Server) error {
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return s }, &mcp.StreamableHTTPOptions{
+		Stateless:    true,
+		JSONResponse: true,
+	})
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+		if err := mux.HandlePath(method, Path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			ctx, err := gateway.IncomingContext(mux, r, "", Path)
+			if err != nil {
+				_, outbound := runtime.MarshalerForRequest(mux, r)
+				runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+				return
+			}
+
+			handler.ServeHTTP(w, r.WithContext(ctx))
+		}); err != nil {
+			return fmt.Errorf("mcp %s %s: %w", method, Path, err)
+		}
+	}
+
+	return nil
+}
+
+// ToolName returns the name of the tool calling the method, e.g. generate_sequence for GenerateSequence.
+func ToolName(method string) string {
+	var b strings.Builder
+	for i, r := range method {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// decoder decodes tool arguments into requests, treating missing arguments as an empty request.
+func decoder(args json.RawMessage) func(any) error {
+	return func(v any) error {
+		if len(args) == 0 || bytes.Equal(args, []byte("null")) {
+			return nil
+		}
+		if err := protojson.Unmarshal(args, v.(proto.Message)); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid arguments: %v", err)
+		}
+		return nil
+	}
+}
+
+// failure reports the status as a tool error, which assistants can see and recover from,
+// as opposed to protocol errors reserved for calls of unknown tools and malformed messages.
+func failure(s *status.Status) *mcp.CallToolResult {
+	data, _ := json.Marshal(gateway.NewProblem(s, ""))
+	return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: string(data)}}}
+}
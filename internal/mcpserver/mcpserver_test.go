@@ -0,0 +1,169 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"buf.build/go/protovalidate"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/domust/fibonacci/api"
+	"github.com/domust/fibonacci/internal"
+	"github.com/domust/fibonacci/internal/auth"
+	"github.com/domust/fibonacci/internal/gateway"
+	"github.com/domust/fibonacci/internal/validation"
+)
+
+// connect returns a client session connected to the server over in-memory transports.
+func connect(t *testing.T, s *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := s.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ss.Close() })
+
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "v1"}, nil).Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cs.Close() })
+
+	return cs
+}
+
+func TestNew(t *testing.T) {
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+
+	var methods []string
+	record := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		methods = append(methods, info.FullMethod)
+		return handler(ctx, req)
+	}
+
+	s, err := New(internal.NewServer(nil), record, validation.New(validator).UnaryInterceptor())
+	require.NoError(t, err)
+	cs := connect(t, s)
+
+	t.Run("list", func(t *testing.T) {
+		result, err := cs.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+
+		var names []string
+		for _, tool := range result.Tools {
+			names = append(names, tool.Name)
+		}
+		require.ElementsMatch(t, []string{"generate_sequence", "get_number"}, names)
+	})
+
+	tests := map[string]struct {
+		tool      string
+		arguments any
+		isError   bool
+		result    string
+		methods   []string
+	}{
+		"generate sequence": {
+			tool:      "generate_sequence",
+			arguments: map[string]any{"length": 5},
+			result:    `{"sequence":["0","1","1","2","3"]}`,
+			methods:   []string{api.Fibonacci_GenerateSequence_FullMethodName},
+		},
+		"get number": {
+			tool:      "get_number",
+			arguments: map[string]any{"index": 9},
+			result:    `{"number":"34"}`,
+			methods:   []string{api.Fibonacci_GetNumber_FullMethodName},
+		},
+		"missing arguments": {
+			tool:    "get_number",
+			result:  `{"number":"0"}`,
+			methods: []string{api.Fibonacci_GetNumber_FullMethodName},
+		},
+		"validation": {
+			tool:      "generate_sequence",
+			arguments: map[string]any{"length": 95},
+			isError:   true,
+			result: `{"type":"about:blank","title":"Bad Request","status":400,
+				"detail":"invalid GenerateSequenceRequest: length: value must be greater than 0 and less than 95",
+				"code":"INVALID_ARGUMENT",
+				"violations":[{"field":"length","rule":"uint32.gt_lt","message":"value must be greater than 0 and less than 95"}]}`,
+			methods: []string{api.Fibonacci_GenerateSequence_FullMethodName},
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			methods = nil
+
+			result, err := cs.CallTool(context.Background(), &mcp.CallToolParams{Name: data.tool, Arguments: data.arguments})
+			require.NoError(t, err)
+			require.Equal(t, data.isError, result.IsError)
+			require.Equal(t, data.methods, methods)
+
+			require.Len(t, result.Content, 1)
+			require.JSONEq(t, data.result, result.Content[0].(*mcp.TextContent).Text)
+			if !data.isError {
+				structured, err := json.Marshal(result.StructuredContent)
+				require.NoError(t, err)
+				require.JSONEq(t, data.result, string(structured))
+			}
+		})
+	}
+
+	t.Run("unknown argument", func(t *testing.T) {
+		result, err := cs.CallTool(context.Background(), &mcp.CallToolParams{Name: "generate_sequence", Arguments: map[string]any{"size": 5}})
+		require.NoError(t, err)
+		require.True(t, result.IsError)
+
+		var problem gateway.Problem
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &problem))
+		require.Equal(t, "INVALID_ARGUMENT", problem.Code)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	var md metadata.MD
+	capture := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ = metadata.FromIncomingContext(ctx)
+		return handler(ctx, req)
+	}
+
+	s, err := New(internal.NewServer(nil), capture)
+	require.NoError(t, err)
+	mux := gateway.NewServeMux()
+	require.NoError(t, Register(mux, s))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: header{auth.APIKeyHeader: "secret"}}
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "v1"}, nil).
+		Connect(context.Background(), &mcp.StreamableClientTransport{Endpoint: srv.URL + Path, HTTPClient: client}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cs.Close() })
+
+	result, err := cs.CallTool(context.Background(), &mcp.CallToolParams{Name: "get_number", Arguments: map[string]any{"index": 3}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.JSONEq(t, `{"number":"2"}`, result.Content[0].(*mcp.TextContent).Text)
+	// headers are passed to interceptors the same way as headers of calls proxied by the gateway
+	require.Equal(t, []string{"secret"}, md.Get(auth.APIKeyHeader))
+}
+
+// header sets headers on every request.
+type header map[string]string
+
+func (h header) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	for k, v := range h {
+		r.Header.Set(k, v)
+	}
+
+	return http.DefaultTransport.RoundTrip(r)
+}
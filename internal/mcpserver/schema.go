@@ -0,0 +1,128 @@
+package mcpserver
+
+import (
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Schema derives the JSON schema of the protojson representation of messages, which tells assistants how to call tools.
+// Bounds of 32-bit integers set by protovalidate rules are carried over, so that assistants can avoid invalid calls,
+// while every rule is still enforced by the validation interceptor. 64-bit integers are strings,
+// the same way as in protojson, since JSON numbers cannot represent all of them.
+func Schema(desc protoreflect.MessageDescriptor) *jsonschema.Schema {
+	return messageSchema(desc, make(map[protoreflect.FullName]bool))
+}
+
+// messageSchema returns the schema of the message, unless it is being derived already,
+// in which case recursive fields are left unconstrained.
+func messageSchema(desc protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) *jsonschema.Schema {
+	// well-known types have their own JSON representations, e.g. timestamps are strings
+	if seen[desc.FullName()] || desc.ParentFile().Package() == "google.protobuf" {
+		return &jsonschema.Schema{}
+	}
+	seen[desc.FullName()] = true
+	defer delete(seen, desc.FullName())
+
+	s := &jsonschema.Schema{
+		Type:       "object",
+		Properties: make(map[string]*jsonschema.Schema),
+		// protojson rejects unknown fields
+		AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
+	}
+
+	fields := desc.Fields()
+	for i := range fields.Len() {
+		field := fields.Get(i)
+		s.Properties[field.JSONName()] = fieldSchema(field, seen)
+
+		if rules, ok := proto.GetExtension(field.Options(), validate.E_Field).(*validate.FieldRules); ok && rules.GetRequired() {
+			s.Required = append(s.Required, field.JSONName())
+		}
+	}
+
+	return s
+}
+
+func fieldSchema(field protoreflect.FieldDescriptor, seen map[protoreflect.FullName]bool) *jsonschema.Schema {
+	switch {
+	case field.IsMap():
+		return &jsonschema.Schema{Type: "object", AdditionalProperties: valueSchema(field.MapValue(), seen)}
+	case field.IsList():
+		return &jsonschema.Schema{Type: "array", Items: valueSchema(field, seen)}
+	}
+
+	return valueSchema(field, seen)
+}
+
+// valueSchema returns the schema of a single value of the field, i.e. of an element when the field is repeated.
+func valueSchema(field protoreflect.FieldDescriptor, seen map[protoreflect.FullName]bool) *jsonschema.Schema {
+	var s *jsonschema.Schema
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		s = &jsonschema.Schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		s = &jsonschema.Schema{Type: "integer"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		s = &jsonschema.Schema{Type: "integer", Minimum: jsonschema.Ptr(0.0)}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		s = &jsonschema.Schema{Type: "string", Pattern: "^-?[0-9]+$"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		s = &jsonschema.Schema{Type: "string", Pattern: "^[0-9]+$"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		s = &jsonschema.Schema{Type: "number"}
+	case protoreflect.StringKind:
+		s = &jsonschema.Schema{Type: "string"}
+	case protoreflect.BytesKind:
+		s = &jsonschema.Schema{Type: "string", ContentEncoding: "base64"}
+	case protoreflect.EnumKind:
+		s = &jsonschema.Schema{Type: "string"}
+		values := field.Enum().Values()
+		for i := range values.Len() {
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(field.Message(), seen)
+	default:
+		return &jsonschema.Schema{}
+	}
+
+	if rules, ok := proto.GetExtension(field.Options(), validate.E_Field).(*validate.FieldRules); ok {
+		switch {
+		case rules.GetUint32() != nil:
+			bound(s, rules.GetUint32())
+		case rules.GetInt32() != nil:
+			bound(s, rules.GetInt32())
+		}
+	}
+
+	return s
+}
+
+// rules of 32-bit integers, which are the only ones represented as JSON numbers.
+type rules[T int32 | uint32] interface {
+	HasGt() bool
+	GetGt() T
+	HasGte() bool
+	GetGte() T
+	HasLt() bool
+	GetLt() T
+	HasLte() bool
+	GetLte() T
+}
+
+func bound[T int32 | uint32](s *jsonschema.Schema, r rules[T]) {
+	if r.HasGt() {
+		s.Minimum, s.ExclusiveMinimum = nil, jsonschema.Ptr(float64(r.GetGt()))
+	}
+	if r.HasGte() {
+		s.Minimum = jsonschema.Ptr(float64(r.GetGte()))
+	}
+	if r.HasLt() {
+		s.ExclusiveMaximum = jsonschema.Ptr(float64(r.GetLt()))
+	}
+	if r.HasLte() {
+		s.Maximum = jsonschema.Ptr(float64(r.GetLte()))
+	}
+}
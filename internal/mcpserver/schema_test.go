@@ -0,0 +1,56 @@
+package mcpserver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/domust/fibonacci/api"
+)
+
+func TestSchema(t *testing.T) {
+	tests := map[string]struct {
+		desc   protoreflect.MessageDescriptor
+		schema string
+	}{
+		"bounds": {
+			desc: (&api.GenerateSequenceRequest{}).ProtoReflect().Descriptor(),
+			schema: `{"type":"object","additionalProperties":false,
+				"properties":{"length":{"type":"integer","exclusiveMinimum":0,"exclusiveMaximum":95}}}`,
+		},
+		"upper bound": {
+			desc: (&api.GetNumberRequest{}).ProtoReflect().Descriptor(),
+			schema: `{"type":"object","additionalProperties":false,
+				"properties":{"index":{"type":"integer","minimum":0,"exclusiveMaximum":94}}}`,
+		},
+		"repeated 64-bit integers": {
+			desc: (&api.GenerateSequenceResponse{}).ProtoReflect().Descriptor(),
+			schema: `{"type":"object","additionalProperties":false,
+				"properties":{"sequence":{"type":"array","items":{"type":"string","pattern":"^[0-9]+$"}}}}`,
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			schema, err := json.Marshal(Schema(data.desc))
+			require.NoError(t, err)
+			require.JSONEq(t, data.schema, string(schema))
+		})
+	}
+}
+
+func TestToolName(t *testing.T) {
+	tests := map[string]string{
+		"GenerateSequence": "generate_sequence",
+		"GetNumber":        "get_number",
+		"Ping":             "ping",
+	}
+
+	for method, expected := range tests {
+		t.Run(method, func(t *testing.T) {
+			require.Equal(t, expected, ToolName(method))
+		})
+	}
+}
@@ -0,0 +1,310 @@
+// Package ratelimit implements per-client token bucket rate limiting of grpc calls.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/domust/fibonacci/internal/auth"
+)
+
+// Default is the method name used for limits that apply to methods without explicit limits.
+const Default = "*"
+
+// RetryAfterHeader is the metadata key carrying the number of seconds after which the call can be retried.
+const RetryAfterHeader = "retry-after"
+
+// sweepInterval is how often buckets of idle clients are discarded.
+const sweepInterval = time.Minute
+
+// DefaultMaxBuckets is the number of buckets kept unless configured otherwise, which bounds the memory
+// held on behalf of clients that keep changing their address.
+const DefaultMaxBuckets = 1 << 16
+
+// Limit is a token bucket configuration.
+type Limit struct {
+	// Rate is the number of calls per second added to the bucket.
+	Rate float64
+	// Burst is the capacity of the bucket.
+	Burst int
+}
+
+// ParseLimits parses comma separated limits in the form of "method=rate:burst",
+// e.g. "/api.v1.Fibonacci/GenerateSequence=10:20,*=100:100".
+func ParseLimits(value string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		method, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("limit %q: missing method", entry)
+		}
+
+		rate, burst, ok := strings.Cut(limit, ":")
+		if !ok {
+			return nil, fmt.Errorf("limit %q: missing burst", entry)
+		}
+
+		var (
+			l   Limit
+			err error
+		)
+		if l.Rate, err = strconv.ParseFloat(rate, 64); err != nil || l.Rate <= 0 {
+			return nil, fmt.Errorf("limit %q: invalid rate", entry)
+		}
+		if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst < 1 {
+			return nil, fmt.Errorf("limit %q: invalid burst", entry)
+		}
+
+		limits[method] = l
+	}
+
+	return limits, nil
+}
+
+// Clock abstracts time for testing.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Option configures the limiter.
+type Option func(*Limiter)
+
+// WithClock replaces the system clock, e.g. with a fake one in tests.
+func WithClock(clock Clock) Option {
+	return func(l *Limiter) {
+		l.clock = clock
+	}
+}
+
+// WithMaxBuckets replaces [DefaultMaxBuckets].
+func WithMaxBuckets(n int) Option {
+	return func(l *Limiter) {
+		l.maxBuckets = n
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type bucketKey struct {
+	method string
+	client string
+}
+
+// Limiter keeps a token bucket per method and client.
+type Limiter struct {
+	limits     map[string]Limit
+	clock      Clock
+	maxBuckets int
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+	swept   time.Time
+}
+
+// New returns limiter enforcing the given limits keyed by full method name, see [Default].
+// Methods without a limit are not limited.
+func New(limits map[string]Limit, opts ...Option) *Limiter {
+	l := &Limiter{
+		limits:     limits,
+		clock:      systemClock{},
+		maxBuckets: DefaultMaxBuckets,
+		buckets:    make(map[bucketKey]*bucket),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.swept = l.clock.Now()
+
+	return l
+}
+
+// Allow takes a token from the bucket of the client for the given method.
+// When the bucket is empty, it returns how long the client has to wait for the next token.
+func (l *Limiter) Allow(method, client string) (bool, time.Duration) {
+	limit, ok := l.limits[method]
+	if !ok {
+		if limit, ok = l.limits[Default]; !ok {
+			return true, 0
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if now.Sub(l.swept) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	key := bucketKey{method: method, client: client}
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= l.maxBuckets {
+			l.sweep(now)
+		}
+		if len(l.buckets) >= l.maxBuckets {
+			l.evict()
+		}
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+
+	return true, 0
+}
+
+// sweep discards buckets that would have been refilled completely, since they are equivalent to new ones.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		limit, ok := l.limits[key.method]
+		if !ok {
+			limit = l.limits[Default]
+		}
+		if b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// evict discards an arbitrary bucket to make room for a new one, which at worst lets its client burst again.
+func (l *Limiter) evict() {
+	for key := range l.buckets {
+		delete(l.buckets, key)
+		return
+	}
+}
+
+// UnaryInterceptor rejects calls exceeding the limit with ResourceExhausted, carrying RetryInfo details and
+// a retry-after header, which the gateway translates into the Retry-After HTTP header.
+// Calls of infrastructure, such as health checks, are never limited, so that probes keep working under load.
+func (l *Limiter) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is the streaming counterpart of [Limiter.UnaryInterceptor], which limits stream creation.
+func (l *Limiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func (l *Limiter) check(ctx context.Context, method string) error {
+	if auth.Public(method) {
+		return nil
+	}
+
+	ok, delay := l.Allow(method, Key(ctx))
+	if ok {
+		return nil
+	}
+
+	seconds := int64(math.Ceil(delay.Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, strconv.FormatInt(seconds, 10)))
+
+	st, err := status.New(codes.ResourceExhausted, "rate limit exceeded").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(delay),
+	})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+
+	return st.Err()
+}
+
+// ProxyAddr is the peer address of calls proxied by the gateway in-process, which no other process can make.
+var ProxyAddr net.Addr = proxyAddr{}
+
+type proxyAddr struct{}
+
+func (proxyAddr) Network() string {
+	return "pipe"
+}
+
+func (proxyAddr) String() string {
+	return "gateway"
+}
+
+// Key identifies the client of the call, preferring the mTLS identity, followed by the authenticated principal
+// and the peer address. Credentials that have not been verified, such as API keys of calls made while authentication
+// is disabled, are ignored, so that clients cannot escape their limits by making them up.
+// Calls proxied by the gateway over its in-process connection are identified by the address of the HTTP client,
+// while forwarded addresses of calls made over the network are ignored, even over loopback, e.g. by sidecars.
+func Key(ctx context.Context) string {
+	p, _ := peer.FromContext(ctx)
+	if p != nil {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+			return "mtls:" + info.State.PeerCertificates[0].Subject.String()
+		}
+	}
+
+	if principal, ok := auth.FromContext(ctx); ok && principal.Name != "" {
+		return "principal:" + principal.Method + ":" + principal.Name
+	}
+
+	if p == nil || p.Addr == nil {
+		return "unknown"
+	}
+
+	if p.Addr == ProxyAddr {
+		// the gateway appends the address of the HTTP client as the last forwarded address
+		md, _ := metadata.FromIncomingContext(ctx)
+		if fwd := md.Get("x-forwarded-for"); len(fwd) > 0 {
+			addrs := strings.Split(fwd[len(fwd)-1], ",")
+			return "addr:" + strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	return "addr:" + host
+}
@@ -0,0 +1,232 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/internal/auth"
+)
+
+const method = "/api.v1.Fibonacci/GenerateSequence"
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits(method + "=0.5:2, *=100:200")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Limit{
+		method:  {Rate: 0.5, Burst: 2},
+		Default: {Rate: 100, Burst: 200},
+	}, limits)
+
+	limits, err = ParseLimits("")
+	require.NoError(t, err)
+	require.Empty(t, limits)
+
+	for _, invalid := range []string{"10:20", "*=10", "*=fast:20", "*=10:0", "*=-1:1"} {
+		_, err := ParseLimits(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("refills over time", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		l := New(map[string]Limit{method: {Rate: 2, Burst: 2}}, WithClock(clock))
+
+		for range 2 {
+			ok, _ := l.Allow(method, "client")
+			require.True(t, ok)
+		}
+
+		ok, delay := l.Allow(method, "client")
+		require.False(t, ok)
+		require.Equal(t, 500*time.Millisecond, delay)
+
+		clock.Advance(250 * time.Millisecond)
+		ok, delay = l.Allow(method, "client")
+		require.False(t, ok)
+		require.Equal(t, 250*time.Millisecond, delay)
+
+		clock.Advance(250 * time.Millisecond)
+		ok, _ = l.Allow(method, "client")
+		require.True(t, ok)
+	})
+
+	t.Run("buckets are per client", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		l := New(map[string]Limit{method: {Rate: 1, Burst: 1}}, WithClock(clock))
+
+		ok, _ := l.Allow(method, "first")
+		require.True(t, ok)
+		ok, _ = l.Allow(method, "first")
+		require.False(t, ok)
+		ok, _ = l.Allow(method, "second")
+		require.True(t, ok)
+	})
+
+	t.Run("default limit", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		l := New(map[string]Limit{Default: {Rate: 1, Burst: 1}}, WithClock(clock))
+
+		ok, _ := l.Allow("/other", "client")
+		require.True(t, ok)
+		ok, _ = l.Allow("/other", "client")
+		require.False(t, ok)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		l := New(nil)
+		for range 100 {
+			ok, _ := l.Allow(method, "client")
+			require.True(t, ok)
+		}
+	})
+
+	t.Run("idle buckets are swept", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		l := New(map[string]Limit{method: {Rate: 1, Burst: 1}}, WithClock(clock))
+
+		l.Allow(method, "idle")
+		clock.Advance(sweepInterval)
+		l.Allow(method, "active")
+		require.Len(t, l.buckets, 1)
+	})
+}
+
+func TestMaxBuckets(t *testing.T) {
+	l := New(map[string]Limit{method: {Rate: 1, Burst: 1}}, WithMaxBuckets(2))
+
+	for i := range 10 {
+		ok, _ := l.Allow(method, fmt.Sprint("client", i))
+		require.True(t, ok)
+		require.LessOrEqual(t, len(l.buckets), 2)
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	interceptor := New(map[string]Limit{method: {Rate: 0.5, Burst: 1}}, WithClock(clock)).UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}})
+
+	resp, err := interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+
+	resp, err = interceptor(ctx, nil, info, handler)
+	require.Nil(t, resp)
+	require.Equal(t, codes.ResourceExhausted.String(), status.Code(err).String())
+
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	retry, ok := details[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, retry.GetRetryDelay().AsDuration())
+
+	clock.Advance(2 * time.Second)
+	_, err = interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+}
+
+func TestPublic(t *testing.T) {
+	interceptor := New(map[string]Limit{Default: {Rate: 0.5, Burst: 1}}).UnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+
+	for range 3 {
+		_, err := interceptor(context.Background(), nil, info, func(context.Context, any) (any, error) { return "ok", nil })
+		require.NoError(t, err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	remote := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	loopback := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+
+	tests := map[string]struct {
+		peer      *peer.Peer
+		metadata  metadata.MD
+		principal *auth.Principal
+		key       string
+	}{
+		"unknown": {
+			key: "unknown",
+		},
+		"peer address": {
+			peer: &peer.Peer{Addr: remote},
+			key:  "addr:10.0.0.1",
+		},
+		"unverified api key is ignored": {
+			peer:     &peer.Peer{Addr: remote},
+			metadata: metadata.Pairs(auth.APIKeyHeader, "secret"),
+			key:      "addr:10.0.0.1",
+		},
+		"principal": {
+			peer:      &peer.Peer{Addr: remote},
+			principal: &auth.Principal{Name: "dashboard", Method: "api-key"},
+			key:       "principal:api-key:dashboard",
+		},
+		"mtls identity": {
+			peer: &peer.Peer{Addr: remote, AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "dashboard"}}},
+			}}},
+			metadata: metadata.Pairs(auth.APIKeyHeader, "secret"),
+			key:      "mtls:CN=dashboard",
+		},
+		"proxied by gateway": {
+			peer:     &peer.Peer{Addr: ProxyAddr},
+			metadata: metadata.Pairs("x-forwarded-for", "192.168.0.1, 10.0.0.2"),
+			key:      "addr:10.0.0.2",
+		},
+		"forwarded address from loopback peer is ignored": {
+			peer:     &peer.Peer{Addr: loopback},
+			metadata: metadata.Pairs("x-forwarded-for", "10.0.0.2"),
+			key:      "addr:127.0.0.1",
+		},
+		"forwarded address from remote peer is ignored": {
+			peer:     &peer.Peer{Addr: remote},
+			metadata: metadata.Pairs("x-forwarded-for", "10.0.0.2"),
+			key:      "addr:10.0.0.1",
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), data.metadata)
+			if data.peer != nil {
+				ctx = peer.NewContext(ctx, data.peer)
+			}
+			if data.principal != nil {
+				ctx = auth.NewContext(ctx, data.principal)
+			}
+			require.Equal(t, data.key, Key(ctx))
+		})
+	}
+}
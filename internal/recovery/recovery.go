@@ -0,0 +1,107 @@
+// Package recovery converts panics in grpc handlers into errors instead of crashing the process.
+package recovery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/internal/telemetry"
+)
+
+// Reason identifies recovered panics in [errdetails.ErrorInfo] details.
+const Reason = "PANIC"
+
+// Recoverer reports recovered panics as crash reports.
+type Recoverer struct {
+	logger  *slog.Logger
+	metrics *telemetry.Metrics
+}
+
+// New returns recoverer reporting panics to the given logger and metrics, both of which are optional.
+func New(logger *slog.Logger, metrics *telemetry.Metrics) *Recoverer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Recoverer{
+		logger:  logger,
+		metrics: metrics,
+	}
+}
+
+// UnaryInterceptor converts panics into Internal errors carrying an error ID, which correlates them with crash reports.
+func (r *Recoverer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				resp, err = nil, r.report(ctx, info.FullMethod, p)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is the streaming counterpart of [Recoverer.UnaryInterceptor].
+func (r *Recoverer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = r.report(ss.Context(), info.FullMethod, p)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+// report records the panic on the active span, in the log and in metrics, and returns the error for the client.
+func (r *Recoverer) report(ctx context.Context, method string, p any) error {
+	stack := string(debug.Stack())
+	id := errorID()
+	cause := fmt.Errorf("panic: %v", p)
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(cause, trace.WithAttributes(
+		semconv.ExceptionStacktrace(stack),
+		semconv.ExceptionEscaped(false),
+	))
+	span.SetStatus(otelcodes.Error, cause.Error())
+
+	r.logger.ErrorContext(ctx, "recovered from panic",
+		slog.String("error_id", id),
+		slog.String("method", method),
+		slog.String("panic", fmt.Sprint(p)),
+		slog.String("stack", stack),
+	)
+	r.metrics.Panic(ctx, method)
+
+	st, err := status.New(codes.Internal, "internal error, id: "+id).WithDetails(&errdetails.ErrorInfo{
+		Reason:   Reason,
+		Domain:   "fibonacci",
+		Metadata: map[string]string{"error_id": id},
+	})
+	if err != nil {
+		return status.Error(codes.Internal, "internal error, id: "+id)
+	}
+
+	return st.Err()
+}
+
+func errorID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
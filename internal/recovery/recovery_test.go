@@ -0,0 +1,119 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/internal/telemetry"
+)
+
+const method = "/api.v1.Fibonacci/GenerateSequence"
+
+type stream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s stream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRecoverer(t *testing.T) {
+	tests := map[string]func(*Recoverer, context.Context) error{
+		"unary": func(r *Recoverer, ctx context.Context) error {
+			_, err := r.UnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+				panic("boom")
+			})
+			return err
+		},
+		"stream": func(r *Recoverer, ctx context.Context) error {
+			return r.StreamInterceptor()(nil, stream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method}, func(any, grpc.ServerStream) error {
+				panic("boom")
+			})
+		},
+	}
+
+	for name, call := range tests {
+		t.Run(name, func(t *testing.T) {
+			var logs bytes.Buffer
+			spans := tracetest.NewSpanRecorder()
+			reader := sdkmetric.NewManualReader()
+
+			metrics, err := telemetry.NewMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+			require.NoError(t, err)
+			r := New(slog.New(slog.NewJSONHandler(&logs, nil)), metrics)
+
+			ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test").Start(context.Background(), method)
+			err = call(r, ctx)
+			span.End()
+
+			// the client receives an error ID instead of the panic
+			require.Equal(t, codes.Internal.String(), status.Code(err).String())
+			require.NotContains(t, err.Error(), "boom")
+			details := status.Convert(err).Details()
+			require.Len(t, details, 1)
+			info, ok := details[0].(*errdetails.ErrorInfo)
+			require.True(t, ok)
+			require.Equal(t, Reason, info.GetReason())
+			id := info.GetMetadata()["error_id"]
+			require.NotEmpty(t, id)
+			require.Contains(t, err.Error(), id)
+
+			// the crash report is logged with the same error ID
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+			require.Equal(t, id, record["error_id"])
+			require.Equal(t, "boom", record["panic"])
+			require.Contains(t, record["stack"], "recovery")
+
+			// the panic is recorded on the active span
+			ended := spans.Ended()
+			require.Len(t, ended, 1)
+			require.Equal(t, otelcodes.Error, ended[0].Status().Code)
+			require.Len(t, ended[0].Events(), 1)
+			var stacktrace bool
+			for _, attr := range ended[0].Events()[0].Attributes {
+				stacktrace = stacktrace || attr.Key == "exception.stacktrace"
+			}
+			require.True(t, stacktrace)
+
+			// and counted
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(context.Background(), &rm))
+			var panics int64
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if m.Name == "fibonacci.panics.count" {
+						for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+							panics += dp.Value
+						}
+					}
+				}
+			}
+			require.Equal(t, int64(1), panics)
+		})
+	}
+}
+
+func TestRecovererWithoutTelemetry(t *testing.T) {
+	r := New(nil, nil)
+	_, err := r.UnaryInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+		panic("boom")
+	})
+	require.Equal(t, codes.Internal.String(), status.Code(err).String())
+}
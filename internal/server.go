@@ -2,51 +2,121 @@ package internal
 
 import (
 	"context"
-	"iter"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/domust/fibonacci/api"
+	apiv2 "github.com/domust/fibonacci/api/v2"
 	"github.com/domust/fibonacci/internal/telemetry"
 )
 
-// Server implements the [api.FibonacciServer] interface.
+// Server implements the [api.FibonacciServer] interface as an adapter over [ServerV2].
 type Server struct {
 	api.UnimplementedFibonacciServer
 
-	metrics *telemetry.Metrics
+	v2 ServerV2
 }
 
 // NewServer returns server configured with instrumentation.
 func NewServer(metrics *telemetry.Metrics) *Server {
 	return &Server{
-		metrics: metrics,
+		v2: ServerV2{metrics: metrics},
 	}
 }
 
 // GenerateSequence is part of the [api.FibonacciServer] interface.
 func (s *Server) GenerateSequence(ctx context.Context, req *api.GenerateSequenceRequest) (*api.GenerateSequenceResponse, error) {
-	s.metrics.Inc(ctx)
-
 	seq := make([]uint64, 0, req.GetLength())
-	for num := range fibonacci(req.GetLength()) {
-		seq = append(seq, num)
+	if req.GetLength() == 0 {
+		// v2 falls back to the default page size instead
+		return &api.GenerateSequenceResponse{Sequence: seq}, nil
+	}
+
+	// sequences of v1 always fit into a single page
+	resp, err := s.v2.ListNumbers(ctx, &apiv2.ListNumbersRequest{PageSize: req.GetLength()})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, num := range resp.GetNumbers() {
+		value, err := uint64Value(num)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, value)
 	}
 
 	return &api.GenerateSequenceResponse{Sequence: seq}, nil
 }
 
-func fibonacci(length uint32) iter.Seq[uint64] {
-	return func(yield func(uint64) bool) {
-		var a, b uint64
+// GetNumber is part of the [api.FibonacciServer] interface.
+func (s *Server) GetNumber(ctx context.Context, req *api.GetNumberRequest) (*api.GetNumberResponse, error) {
+	num, err := s.v2.GetNumber(ctx, &apiv2.GetNumberRequest{Index: req.GetIndex()})
+	if err != nil {
+		return nil, err
+	}
 
-		for i := range uint64(length) {
-			if i <= 1 {
-				a += i
-			}
-			a, b = b, a+b
+	value, err := uint64Value(num)
+	if err != nil {
+		return nil, err
+	}
 
-			if !yield(b) {
-				return
-			}
-		}
+	return &api.GetNumberResponse{Number: value}, nil
+}
+
+// StreamSequence is part of the [api.FibonacciServer] interface.
+func (s *Server) StreamSequence(req *api.GenerateSequenceRequest, stream grpc.ServerStreamingServer[api.StreamSequenceResponse]) error {
+	return s.v2.StreamNumbers(&apiv2.StreamNumbersRequest{Length: req.GetLength()}, sequenceStream{stream})
+}
+
+// sequenceStream sends numbers of v2 streams as terms of v1 streams.
+type sequenceStream struct {
+	grpc.ServerStreamingServer[api.StreamSequenceResponse]
+}
+
+func (s sequenceStream) Send(num *apiv2.Number) error {
+	value, err := uint64Value(num)
+	if err != nil {
+		return err
+	}
+
+	return s.ServerStreamingServer.Send(&api.StreamSequenceResponse{Index: num.GetIndex(), Value: value})
+}
+
+// uint64Value returns the value of the number, which v1 is only able to represent when it fits into 64 bits.
+func uint64Value(num *apiv2.Number) (uint64, error) {
+	value, ok := num.GetValue().(*apiv2.Number_Uint64)
+	if !ok {
+		return 0, status.Errorf(codes.OutOfRange, "term %d does not fit into 64 bits", num.GetIndex())
+	}
+
+	return value.Uint64, nil
+}
+
+// SelfTest verifies that the largest term of v1 sequences is computed correctly.
+func SelfTest(ctx context.Context) error {
+	const (
+		length = 94
+		last   = 12200160415121876738
+	)
+
+	var (
+		got  uint64
+		fits bool
+	)
+	for _, term := range fibonacci(ctx, length) {
+		got, fits = term.Uint64(), term.IsUint64()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+
+	if !fits || got != last {
+		return fmt.Errorf("fibonacci(%d) ended with %d instead of %d", length, got, uint64(last))
+	}
+
+	return nil
 }
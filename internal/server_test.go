@@ -2,6 +2,8 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log"
 	"net"
 	"testing"
@@ -60,6 +62,30 @@ func TestServer(t *testing.T) {
 		require.Nil(t, resp)
 	})
 
+	t.Run("streaming", func(t *testing.T) {
+		stream, err := client.StreamSequence(context.Background(), &api.GenerateSequenceRequest{Length: 10})
+		require.NoError(t, err)
+
+		var seq []uint64
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			require.Equal(t, uint32(len(seq)), resp.GetIndex())
+			seq = append(seq, resp.GetValue())
+		}
+		require.Equal(t, []uint64{0, 1, 1, 2, 3, 5, 8, 13, 21, 34}, seq)
+	})
+
+	t.Run("streaming validation", func(t *testing.T) {
+		stream, err := client.StreamSequence(context.Background(), &api.GenerateSequenceRequest{Length: 95})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+	})
+
 	tests := map[string]struct {
 		input  uint32
 		output []uint64
@@ -99,3 +125,42 @@ func TestServer(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNumber(t *testing.T) {
+	tests := map[string]struct {
+		index  uint32
+		number uint64
+	}{
+		"first":  {index: 0, number: 0},
+		"second": {index: 1, number: 1},
+		"tenth":  {index: 9, number: 34},
+		"last":   {index: 93, number: 12200160415121876738},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := NewServer(nil).GetNumber(context.Background(), &api.GetNumberRequest{Index: data.index})
+			require.NoError(t, err)
+			require.Equal(t, data.number, resp.GetNumber())
+		})
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	require.NoError(t, SelfTest(context.Background()))
+}
+
+func TestCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resp, err := NewServer(nil).GenerateSequence(ctx, &api.GenerateSequenceRequest{Length: 94})
+	require.Equal(t, codes.Canceled.String(), status.Code(err).String())
+	require.Nil(t, resp)
+
+	var generated int
+	for range fibonacci(ctx, 94) {
+		generated++
+	}
+	require.Zero(t, generated)
+}
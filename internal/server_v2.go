@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"iter"
+	"math/big"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apiv2 "github.com/domust/fibonacci/api/v2"
+	"github.com/domust/fibonacci/internal/telemetry"
+)
+
+// checkInterval is the number of terms generated between checks of cancellation.
+const checkInterval = 16
+
+// maxLength is the length of the sequence served by v2, so that calls bypassing validation,
+// such as the ones made by v1, cannot generate more either.
+const maxLength = apiv2.MaxLength
+
+// ServerV2 implements the [apiv2.FibonacciServer] interface.
+type ServerV2 struct {
+	apiv2.UnimplementedFibonacciServer
+
+	metrics *telemetry.Metrics
+}
+
+// NewServerV2 returns server configured with instrumentation.
+func NewServerV2(metrics *telemetry.Metrics) *ServerV2 {
+	return &ServerV2{
+		metrics: metrics,
+	}
+}
+
+// ListNumbers is part of the [apiv2.FibonacciServer] interface.
+func (s *ServerV2) ListNumbers(ctx context.Context, req *apiv2.ListNumbersRequest) (*apiv2.ListNumbersResponse, error) {
+	s.metrics.Inc(ctx)
+
+	start, err := apiv2.ParsePageToken(req.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if start >= maxLength {
+		return nil, status.Errorf(codes.InvalidArgument, "page token starts past the last term %d", maxLength-1)
+	}
+
+	size := req.GetPageSize()
+	if size == 0 {
+		size = apiv2.DefaultPageSize
+	}
+	end := uint32(min(uint64(start)+uint64(size), maxLength))
+	numbers := make([]*apiv2.Number, 0, end-start)
+	for index, term := range fibonacci(ctx, end) {
+		if index >= start {
+			numbers = append(numbers, number(index, term))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	resp := &apiv2.ListNumbersResponse{Numbers: numbers}
+	if end < maxLength {
+		resp.NextPageToken = apiv2.PageToken(end)
+	}
+
+	return resp, nil
+}
+
+// GetNumber is part of the [apiv2.FibonacciServer] interface.
+func (s *ServerV2) GetNumber(ctx context.Context, req *apiv2.GetNumberRequest) (*apiv2.Number, error) {
+	s.metrics.Inc(ctx)
+
+	if req.GetIndex() >= maxLength {
+		return nil, status.Errorf(codes.InvalidArgument, "index must be less than %d", maxLength)
+	}
+
+	var num *apiv2.Number
+	for index, term := range fibonacci(ctx, req.GetIndex()+1) {
+		if index == req.GetIndex() {
+			num = number(index, term)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+
+	return num, nil
+}
+
+// StreamNumbers is part of the [apiv2.FibonacciServer] interface.
+func (s *ServerV2) StreamNumbers(req *apiv2.StreamNumbersRequest, stream grpc.ServerStreamingServer[apiv2.Number]) error {
+	ctx := stream.Context()
+	s.metrics.Inc(ctx)
+
+	for index, term := range fibonacci(ctx, min(req.GetLength(), maxLength)) {
+		// sending blocks while flow control windows are exhausted, which holds generation back for slow clients
+		if err := stream.Send(number(index, term)); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	return nil
+}
+
+// number represents the term as a 64-bit integer whenever it fits.
+func number(index uint32, term *big.Int) *apiv2.Number {
+	if term.IsUint64() {
+		return &apiv2.Number{Index: index, Value: &apiv2.Number_Uint64{Uint64: term.Uint64()}}
+	}
+
+	return &apiv2.Number{Index: index, Value: &apiv2.Number_Big{Big: term.String()}}
+}
+
+// fibonacci yields indexes and terms of the sequence of the given length, stopping early once the context is done.
+// Terms are reused by subsequent iterations, so they must not be retained.
+func fibonacci(ctx context.Context, length uint32) iter.Seq2[uint32, *big.Int] {
+	return func(yield func(uint32, *big.Int) bool) {
+		a, b := new(big.Int), big.NewInt(1)
+
+		for i := range length {
+			if i%checkInterval == 0 && ctx.Err() != nil {
+				return
+			}
+
+			if !yield(i, a) {
+				return
+			}
+			a, b = b, a.Add(a, b)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	apiv2 "github.com/domust/fibonacci/api/v2"
+)
+
+func TestListNumbers(t *testing.T) {
+	s := NewServerV2(nil)
+
+	t.Run("pagination", func(t *testing.T) {
+		var (
+			values []uint64
+			token  string
+		)
+		for range 3 {
+			resp, err := s.ListNumbers(context.Background(), &apiv2.ListNumbersRequest{PageSize: 4, PageToken: token})
+			require.NoError(t, err)
+			for _, num := range resp.GetNumbers() {
+				require.Equal(t, uint32(len(values)), num.GetIndex())
+				values = append(values, num.GetUint64())
+			}
+			token = resp.GetNextPageToken()
+		}
+		require.Equal(t, []uint64{0, 1, 1, 2, 3, 5, 8, 13, 21, 34, 55, 89}, values)
+		require.Equal(t, apiv2.PageToken(12), token)
+	})
+
+	t.Run("default page size", func(t *testing.T) {
+		resp, err := s.ListNumbers(context.Background(), &apiv2.ListNumbersRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.GetNumbers(), apiv2.DefaultPageSize)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		resp, err := s.ListNumbers(context.Background(), &apiv2.ListNumbersRequest{PageSize: 1000, PageToken: apiv2.PageToken(maxLength - 10)})
+		require.NoError(t, err)
+		require.Len(t, resp.GetNumbers(), 10)
+		require.Equal(t, uint32(maxLength-1), resp.GetNumbers()[9].GetIndex())
+		require.Empty(t, resp.GetNextPageToken())
+	})
+
+	tests := map[string]string{
+		"malformed token": "not a token",
+		"truncated token": apiv2.PageToken(1000)[:1],
+		"past last term":  apiv2.PageToken(maxLength),
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := s.ListNumbers(context.Background(), &apiv2.ListNumbersRequest{PageToken: token})
+			require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+			require.Nil(t, resp)
+		})
+	}
+}
+
+func TestGetNumberV2(t *testing.T) {
+	tests := map[string]struct {
+		index  uint32
+		number *apiv2.Number
+	}{
+		"first": {
+			index:  0,
+			number: &apiv2.Number{Index: 0, Value: &apiv2.Number_Uint64{Uint64: 0}},
+		},
+		"last 64-bit": {
+			index:  93,
+			number: &apiv2.Number{Index: 93, Value: &apiv2.Number_Uint64{Uint64: 12200160415121876738}},
+		},
+		"first big": {
+			index:  94,
+			number: &apiv2.Number{Index: 94, Value: &apiv2.Number_Big{Big: "19740274219868223167"}},
+		},
+		"hundredth": {
+			index:  100,
+			number: &apiv2.Number{Index: 100, Value: &apiv2.Number_Big{Big: "354224848179261915075"}},
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			num, err := NewServerV2(nil).GetNumber(context.Background(), &apiv2.GetNumberRequest{Index: data.index})
+			require.NoError(t, err)
+			require.Equal(t, data.number.GetIndex(), num.GetIndex())
+			require.Equal(t, data.number.GetValue(), num.GetValue())
+		})
+	}
+
+	t.Run("out of range", func(t *testing.T) {
+		num, err := NewServerV2(nil).GetNumber(context.Background(), &apiv2.GetNumberRequest{Index: maxLength})
+		require.Equal(t, codes.InvalidArgument.String(), status.Code(err).String())
+		require.Nil(t, num)
+	})
+}
+
+func TestPageToken(t *testing.T) {
+	for _, index := range []uint32{0, 1, 127, 128, maxLength, 1<<32 - 1} {
+		got, err := apiv2.ParsePageToken(apiv2.PageToken(index))
+		require.NoError(t, err)
+		require.Equal(t, index, got)
+	}
+}
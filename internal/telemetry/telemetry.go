@@ -3,11 +3,17 @@ package telemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
+	"net/url"
+	"os"
 
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
@@ -33,6 +39,7 @@ type Telemetry struct {
 	propagator propagation.TextMapPropagator
 	metrics    metric.MeterProvider
 	logs       log.LoggerProvider
+	shutdowns  []func(context.Context) error
 }
 
 // ServerOption is required to start a span when the server's Recv method is called.
@@ -45,6 +52,33 @@ func (t *Telemetry) ServerOption() grpc.ServerOption {
 	})
 }
 
+// DialOption propagates trace context from grpc clients, such as the gateway, to the grpc server.
+func (t *Telemetry) DialOption() grpc.DialOption {
+	return opentelemetry.DialOption(opentelemetry.Options{
+		TraceOptions: tracing.TraceOptions{
+			TracerProvider:    t.traces,
+			TextMapPropagator: t.propagator,
+		},
+	})
+}
+
+// Middleware starts a server span for every HTTP request, continuing the trace propagated by the client.
+func (t *Telemetry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := t.traces.Tracer(scope).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // UnaryInterceptor is required to create a method specific span from the parent (Recv) span.
 func (t *Telemetry) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
@@ -55,6 +89,19 @@ func (t *Telemetry) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
+// StreamInterceptor is the streaming counterpart of [Telemetry.UnaryInterceptor], which creates a span per stream.
+func (t *Telemetry) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := t.traces.Tracer(scope).Start(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		wrapped := middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+
+		return handler(srv, wrapped)
+	}
+}
+
 // Meter returns a new meter for dependency injection.
 func (t *Telemetry) Meter() metric.Meter {
 	return t.metrics.Meter(scope)
@@ -65,6 +112,41 @@ func (t *Telemetry) Logger() *slog.Logger {
 	return otelslog.NewLogger(scope, otelslog.WithLoggerProvider(t.logs))
 }
 
+// Shutdown flushes buffered telemetry signals and stops the exporters.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, shutdown := range t.shutdowns {
+		errs = append(errs, shutdown(ctx))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Ping checks whether the OTLP collector accepts connections.
+func (t *Telemetry) Ping(ctx context.Context) error {
+	endpoint := "localhost:4317"
+	if env, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_ENDPOINT"); ok {
+		endpoint = collectorAddress(env)
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp4", endpoint)
+	if err != nil {
+		return fmt.Errorf("collector: %w", err)
+	}
+
+	return conn.Close()
+}
+
+// collectorAddress returns the host and port of the endpoint, which is either a URL or,
+// as allowed for gRPC exporters, a bare host:port such as "collector:4317".
+func collectorAddress(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+
+	return endpoint
+}
+
 // NewTelemetry is used to provision dependencies required for exporting telemetry signals.
 func NewTelemetry(ctx context.Context) (*Telemetry, error) {
 	traces, err := otlptracegrpc.New(ctx, otlptracegrpc.WithDialOption(grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
@@ -89,17 +171,28 @@ func NewTelemetry(ctx context.Context) (*Telemetry, error) {
 		return nil, err
 	}
 
+	tp := newTracerProvider(traces, rsc)
+	mp := newMeterProvider(metrics, rsc)
+	lp := newLoggerProvider(logs, rsc)
+
 	return &Telemetry{
-		traces:     newTracerProvider(traces, rsc),
+		traces:     tp,
 		propagator: propagation.TraceContext{},
-		metrics:    newMeterProvider(metrics, rsc),
-		logs:       newLoggerProvider(logs, rsc),
+		metrics:    mp,
+		logs:       lp,
+		shutdowns:  []func(context.Context) error{tp.Shutdown, mp.Shutdown, lp.Shutdown},
 	}, nil
 }
 
 // Metrics encapsulates all metrics fox export.
 type Metrics struct {
-	counter metric.Int64Counter
+	counter    metric.Int64Counter
+	panics     metric.Int64Counter
+	queue      metric.Int64UpDownCounter
+	rejections metric.Int64Counter
+	ratio      metric.Float64Histogram
+	saved      metric.Int64UpDownCounter
+	dedup      metric.Int64Counter
 }
 
 // Inc adds to the API request counter.
@@ -110,6 +203,49 @@ func (m *Metrics) Inc(ctx context.Context) {
 	m.counter.Add(ctx, 1)
 }
 
+// Panic adds to the counter of panics recovered while handling calls of the given method.
+func (m *Metrics) Panic(ctx context.Context, method string) {
+	if m == nil {
+		return
+	}
+	m.panics.Add(ctx, 1, metric.WithAttributes(semconv.RPCMethod(method)))
+}
+
+// Queued adjusts the number of calls waiting for admission.
+func (m *Metrics) Queued(ctx context.Context, delta int64) {
+	if m == nil {
+		return
+	}
+	m.queue.Add(ctx, delta)
+}
+
+// Reject adds to the counter of calls of the given method rejected by admission control for the given reason.
+func (m *Metrics) Reject(ctx context.Context, method, reason string) {
+	if m == nil {
+		return
+	}
+	m.rejections.Add(ctx, 1, metric.WithAttributes(semconv.RPCMethod(method), attribute.String("reason", reason)))
+}
+
+// Compressed records the compression ratio and bytes saved by compressing a response with the given coding.
+func (m *Metrics) Compressed(ctx context.Context, protocol, coding string, uncompressed, compressed int) {
+	if m == nil || compressed == 0 {
+		return
+	}
+	attrs := metric.WithAttributes(attribute.String("protocol", protocol), attribute.String("encoding", coding))
+	m.ratio.Record(ctx, float64(uncompressed)/float64(compressed), attrs)
+	m.saved.Add(ctx, int64(uncompressed-compressed), attrs)
+}
+
+// Deduplicated adds to the counter of calls of the given method answered with the response of an identical call,
+// where the source tells whether the response was stored or shared while both calls were in flight.
+func (m *Metrics) Deduplicated(ctx context.Context, method, source string) {
+	if m == nil {
+		return
+	}
+	m.dedup.Add(ctx, 1, metric.WithAttributes(semconv.RPCMethod(method), attribute.String("source", source)))
+}
+
 // NewMetrics creates metrics from a given meter.
 func NewMetrics(meter metric.Meter) (*Metrics, error) {
 	counter, err := meter.Int64Counter("fibonacci.requests.count")
@@ -117,8 +253,44 @@ func NewMetrics(meter metric.Meter) (*Metrics, error) {
 		return nil, fmt.Errorf("counter: %w", err)
 	}
 
+	panics, err := meter.Int64Counter("fibonacci.panics.count")
+	if err != nil {
+		return nil, fmt.Errorf("panics: %w", err)
+	}
+
+	queue, err := meter.Int64UpDownCounter("fibonacci.admission.queue")
+	if err != nil {
+		return nil, fmt.Errorf("queue: %w", err)
+	}
+
+	rejections, err := meter.Int64Counter("fibonacci.admission.rejections.count")
+	if err != nil {
+		return nil, fmt.Errorf("rejections: %w", err)
+	}
+
+	ratio, err := meter.Float64Histogram("fibonacci.compression.ratio")
+	if err != nil {
+		return nil, fmt.Errorf("ratio: %w", err)
+	}
+
+	saved, err := meter.Int64UpDownCounter("fibonacci.compression.saved", metric.WithUnit("By"))
+	if err != nil {
+		return nil, fmt.Errorf("saved: %w", err)
+	}
+
+	dedup, err := meter.Int64Counter("fibonacci.idempotency.hits.count")
+	if err != nil {
+		return nil, fmt.Errorf("dedup: %w", err)
+	}
+
 	return &Metrics{
-		counter: counter,
+		counter:    counter,
+		panics:     panics,
+		queue:      queue,
+		rejections: rejections,
+		ratio:      ratio,
+		saved:      saved,
+		dedup:      dedup,
 	}, nil
 }
 
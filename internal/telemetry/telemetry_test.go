@@ -0,0 +1,23 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectorAddress(t *testing.T) {
+	tests := map[string]string{
+		"http://collector:4317":  "collector:4317",
+		"https://collector:4317": "collector:4317",
+		"collector:4317":         "collector:4317",
+		"10.0.0.1:4317":          "10.0.0.1:4317",
+		"localhost:4317":         "localhost:4317",
+	}
+
+	for endpoint, address := range tests {
+		t.Run(endpoint, func(t *testing.T) {
+			require.Equal(t, address, collectorAddress(endpoint))
+		})
+	}
+}
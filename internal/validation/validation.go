@@ -0,0 +1,98 @@
+// Package validation rejects invalid requests with field violations understood by any grpc client.
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Validator validates requests against their protovalidate rules.
+type Validator struct {
+	validator protovalidate.Validator
+}
+
+// New returns validator reporting violations as [errdetails.BadRequest] details.
+func New(validator protovalidate.Validator) *Validator {
+	return &Validator{validator: validator}
+}
+
+// Validate returns InvalidArgument with a field violation per broken rule when the message is invalid.
+func (v *Validator) Validate(m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "unsupported message type: %T", m)
+	}
+
+	err := v.validator.Validate(msg)
+	if err == nil {
+		return nil
+	}
+
+	var verr *protovalidate.ValidationError
+	if !errors.As(err, &verr) {
+		// rules that fail to compile are a server bug rather than a client mistake
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(verr.Violations))
+	messages := make([]string, 0, len(verr.Violations))
+	for _, violation := range verr.Violations {
+		field := protovalidate.FieldPathString(violation.Proto.GetField())
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Reason:      violation.Proto.GetRuleId(),
+			Description: violation.Proto.GetMessage(),
+		})
+		messages = append(messages, fmt.Sprintf("%s: %s", field, violation.Proto.GetMessage()))
+	}
+
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid %s: %s", msg.ProtoReflect().Descriptor().Name(), strings.Join(messages, ", ")))
+	// protovalidate violations are kept for clients that understand them
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}, verr.ToProto())
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// UnaryInterceptor rejects invalid requests.
+func (v *Validator) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := v.Validate(req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rejects invalid messages as they are received.
+func (v *Validator) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &stream{ServerStream: ss, validator: v})
+	}
+}
+
+type stream struct {
+	grpc.ServerStream
+
+	validator *Validator
+}
+
+func (s *stream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return s.validator.Validate(m)
+}
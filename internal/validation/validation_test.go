@@ -0,0 +1,99 @@
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"buf.build/go/protovalidate"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/domust/fibonacci/api"
+)
+
+func TestValidate(t *testing.T) {
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+	v := New(validator)
+
+	tests := map[string]struct {
+		length    uint32
+		violation *errdetails.BadRequest_FieldViolation
+		message   string
+	}{
+		"too short": {
+			length: 0,
+			violation: &errdetails.BadRequest_FieldViolation{
+				Field:       "length",
+				Reason:      "uint32.gt_lt",
+				Description: "value must be greater than 0 and less than 95",
+			},
+			message: "invalid GenerateSequenceRequest: length: value must be greater than 0 and less than 95",
+		},
+		"overflow": {
+			length: 95,
+			violation: &errdetails.BadRequest_FieldViolation{
+				Field:       "length",
+				Reason:      "uint32.gt_lt",
+				Description: "value must be greater than 0 and less than 95",
+			},
+			message: "invalid GenerateSequenceRequest: length: value must be greater than 0 and less than 95",
+		},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.UnaryInterceptor()(context.Background(), &api.GenerateSequenceRequest{Length: data.length}, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+				t.Fatal("invalid request reached the handler")
+				return nil, nil
+			})
+
+			s := status.Convert(err)
+			require.Equal(t, codes.InvalidArgument, s.Code())
+			require.Equal(t, data.message, s.Message())
+
+			details := s.Details()
+			require.Len(t, details, 2)
+			br, ok := details[0].(*errdetails.BadRequest)
+			require.True(t, ok)
+			require.Len(t, br.GetFieldViolations(), 1)
+			require.Equal(t, data.violation.GetField(), br.GetFieldViolations()[0].GetField())
+			require.Equal(t, data.violation.GetReason(), br.GetFieldViolations()[0].GetReason())
+			require.Equal(t, data.violation.GetDescription(), br.GetFieldViolations()[0].GetDescription())
+
+			violations, ok := details[1].(*validate.Violations)
+			require.True(t, ok)
+			require.Equal(t, data.violation.GetReason(), violations.GetViolations()[0].GetRuleId())
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		require.NoError(t, v.Validate(&api.GenerateSequenceRequest{Length: 94}))
+	})
+}
+
+type invalidStream struct {
+	grpc.ServerStream
+}
+
+func (invalidStream) RecvMsg(m any) error {
+	m.(*api.GenerateSequenceRequest).Length = 95
+	return nil
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	validator, err := protovalidate.New()
+	require.NoError(t, err)
+
+	err = New(validator).StreamInterceptor()(nil, invalidStream{}, &grpc.StreamServerInfo{}, func(_ any, ss grpc.ServerStream) error {
+		return ss.RecvMsg(&api.GenerateSequenceRequest{})
+	})
+
+	s := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, s.Code())
+	require.IsType(t, &errdetails.BadRequest{}, s.Details()[0])
+}
@@ -2,77 +2,317 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
 	"net"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 
 	"buf.build/go/protovalidate"
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/health"
+	grpchealth "google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/domust/fibonacci/api"
+	apiv2 "github.com/domust/fibonacci/api/v2"
 	"github.com/domust/fibonacci/internal"
+	"github.com/domust/fibonacci/internal/accesslog"
+	"github.com/domust/fibonacci/internal/admission"
+	"github.com/domust/fibonacci/internal/auth"
+	"github.com/domust/fibonacci/internal/authz"
+	"github.com/domust/fibonacci/internal/browser"
+	"github.com/domust/fibonacci/internal/budget"
+	"github.com/domust/fibonacci/internal/caching"
+	"github.com/domust/fibonacci/internal/compression"
+	"github.com/domust/fibonacci/internal/config"
+	"github.com/domust/fibonacci/internal/gateway"
 	rpc "github.com/domust/fibonacci/internal/grpc"
+	"github.com/domust/fibonacci/internal/health"
+	"github.com/domust/fibonacci/internal/httpserver"
+	"github.com/domust/fibonacci/internal/idempotency"
+	"github.com/domust/fibonacci/internal/jsonrpc"
+	"github.com/domust/fibonacci/internal/lifecycle"
+	"github.com/domust/fibonacci/internal/mcpserver"
+	"github.com/domust/fibonacci/internal/ratelimit"
+	"github.com/domust/fibonacci/internal/recovery"
 	"github.com/domust/fibonacci/internal/telemetry"
 )
 
 func main() {
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	if err := run(); err != nil {
+		// telemetry is shut down at this point, so the error is written directly to stderr
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
 	tel, err := telemetry.NewTelemetry(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer func() {
+		// the signal context is already done at this point, so flushing needs its own deadline
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		if shutdownErr := tel.Shutdown(ctx); shutdownErr != nil {
+			err = errors.Join(err, fmt.Errorf("telemetry shutdown: %w", shutdownErr))
+		}
+	}()
 
 	slog.SetDefault(tel.Logger()) // comment out in order to debug startup failures locally
 	metrics, err := telemetry.NewMetrics(tel.Meter())
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	validator, err := protovalidate.New()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	var limiter *ratelimit.Limiter
+	if len(cfg.RateLimits) > 0 {
+		limiter = ratelimit.New(cfg.RateLimits)
+	}
+
+	var controller *admission.Controller
+	if cfg.Admission.MaxInFlight > 0 {
+		controller = admission.New(cfg.Admission, metrics)
+	}
+
+	compressor := compression.New(int(cfg.CompressionThreshold), metrics)
+	compressor.Register()
+
+	var cache *caching.Cache
+	if cfg.CacheMaxAge > 0 {
+		cache = caching.New(cfg.CacheMaxAge)
+	}
+
+	var store *idempotency.Store
+	if cfg.IdempotencyTTL > 0 {
+		store = idempotency.New(cfg.IdempotencyTTL, metrics)
+	}
+
+	var authenticator *auth.Authenticator
+	if cfg.AuthAPIKeys != "" || cfg.AuthJWKS != "" {
+		var opts []auth.Option
+		if cfg.AuthAPIKeys != "" {
+			opts = append(opts, auth.WithAPIKeys(cfg.AuthAPIKeys))
+		}
+		if cfg.AuthJWKS != "" {
+			opts = append(opts, auth.WithJWKS(cfg.AuthJWKS, cfg.AuthIssuer, cfg.AuthAudience))
+		}
+
+		if authenticator, err = auth.New(opts...); err != nil {
+			return err
+		}
+	}
+
+	var authorizer *authz.Authorizer
+	if cfg.AuthzPolicies != "" {
+		policies, err := authz.Load(cfg.AuthzPolicies)
+		if err != nil {
+			return err
+		}
+
+		if authorizer, err = authz.New(policies); err != nil {
+			return err
+		}
+	}
+
+	var accessLogger *accesslog.Logger
+	if cfg.AccessLog {
+		accessLogger = accesslog.New(tel.Logger(), cfg.AccessLogSampleRate, cfg.AccessLogRedact...)
+	}
+
+	localOpts := []rpc.Option{
+		rpc.WithAccessLog(accessLogger),
+		rpc.WithAdmission(controller),
+		rpc.WithCompression(compressor),
+		rpc.WithCaching(cache),
+		rpc.WithIdempotency(store),
+		rpc.WithBudget(budget.New(cfg.CostPerWord, cfg.MaxBudget)),
+		rpc.WithRecovery(recovery.New(tel.Logger(), metrics)),
+	}
+	server := internal.NewServer(metrics)
+	if cfg.MCPStdio {
+		// the assistant that started the process is trusted, so its tool calls are neither authenticated nor rate limited
+		tools, err := mcpserver.New(server, rpc.UnaryInterceptors(tel, validator, localOpts...)...)
+		if err != nil {
+			return err
+		}
+
+		log.Println("serving mcp over stdio")
+		if err := tools.Run(ctx, &mcp.StdioTransport{}); err != nil && ctx.Err() == nil {
+			return err
+		}
+		return nil
 	}
 
-	gs := rpc.NewServer(tel, validator)
-	hs := health.NewServer()
-	api.RegisterFibonacciServer(gs, internal.NewServer(metrics))
+	serverOpts := append(localOpts,
+		rpc.WithRateLimit(limiter),
+		rpc.WithAuth(authenticator),
+		rpc.WithAuthz(authorizer),
+	)
+	gs := rpc.NewServer(tel, validator, append(serverOpts, rpc.WithReflection(cfg.Reflection))...)
+	hs := grpchealth.NewServer()
+	api.RegisterFibonacciServer(gs, server)
+	apiv2.RegisterFibonacciServer(gs, internal.NewServerV2(metrics))
 	grpc_health_v1.RegisterHealthServer(gs, hs)
 
-	go func() {
-		<-ctx.Done()
-		cancel()
-		hs.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
-		gs.GracefulStop()
-	}()
+	h := health.New(hs, api.Fibonacci_ServiceDesc.ServiceName, apiv2.Fibonacci_ServiceDesc.ServiceName)
+	h.Add(
+		health.Check{Name: "self-test", Kind: health.Liveness, Check: internal.SelfTest},
+		health.Check{Name: "telemetry", Kind: health.Informational, Check: tel.Ping},
+	)
 
 	lis, err := net.Listen("tcp4", ":8080")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	log.Printf("starting grpc server on %s\n", lis.Addr().String())
-	go func() {
-		if err := gs.Serve(lis); err != nil {
-			log.Fatal(err)
-		}
-	}()
+	// the gateway connects in-process, so that the grpc server can trust the client addresses it forwards,
+	// and its connection outlives the signal context, so that requests can be proxied while draining
+	pipe := rpc.NewPipe()
+	conn, err := grpc.NewClient("passthrough:///gateway",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		pipe.DialOption(),
+		tel.DialOption(),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	proxy := gateway.NewServeMux()
+	if err := api.RegisterFibonacciHandler(ctx, proxy, conn); err != nil {
+		return err
+	}
+
+	if err := apiv2.RegisterFibonacciHandler(ctx, proxy, conn); err != nil {
+		return err
+	}
+
+	if err := gateway.RegisterOpenAPI(proxy); err != nil {
+		return err
+	}
+
+	if err := gateway.RegisterHealth(proxy, h); err != nil {
+		return err
+	}
+
+	client := api.NewFibonacciClient(conn)
+	if err := gateway.RegisterWebSocket(proxy, client, cfg.WebSocket); err != nil {
+		return err
+	}
 
-	proxy := runtime.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
-	err = api.RegisterFibonacciHandlerFromEndpoint(ctx, proxy, "0.0.0.0:8080", opts)
+	if err := gateway.RegisterEventStream(proxy, client, cfg.EventStream); err != nil {
+		return err
+	}
+
+	// JSON-RPC calls are served in-process, but authenticated, limited and validated the same way as grpc calls
+	if err := jsonrpc.Register(proxy, server, rpc.UnaryInterceptors(tel, validator, serverOpts...)...); err != nil {
+		return err
+	}
+
+	tools, err := mcpserver.New(server, rpc.UnaryInterceptors(tel, validator, serverOpts...)...)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if err := mcpserver.Register(proxy, tools); err != nil {
+		return err
 	}
 
-	log.Println("starting grpc proxy on 0.0.0.0:8081")
-	if err := http.ListenAndServe(":8081", proxy); err != nil {
-		log.Fatal(err)
+	if cfg.Reflection {
+		if err := gateway.RegisterDescriptors(proxy); err != nil {
+			return err
+		}
+	}
+
+	hl, err := net.Listen("tcp4", ":8081")
+	if err != nil {
+		return err
 	}
+	var logging, cors, security gateway.Middleware
+	if accessLogger != nil {
+		logging = accessLogger.Middleware
+	}
+	if len(cfg.CORS.Origins) > 0 {
+		cors = browser.New(cfg.CORS).Middleware
+	}
+	if cfg.SecurityHeaders {
+		security = browser.SecurityHeaders
+	}
+	handler := gateway.Chain(proxy,
+		// telemetry is outermost, so that access log records and proxied calls share the request trace
+		tel.Middleware,
+		logging,
+		security,
+		// preflight requests are answered before reaching any other middleware
+		cors,
+		// caching wraps compression, so that entity tags distinguish compressed representations
+		caching.Middleware,
+		compressor.Middleware,
+		gateway.Format,
+		func(next http.Handler) http.Handler { return httpserver.LimitBody(next, cfg.HTTP.MaxBodyBytes) },
+	)
+	hsrv := httpserver.New(handler, cfg.HTTP)
+
+	var g lifecycle.Group
+	g.Add(lifecycle.Signal(ctx, cfg.ShutdownDrain, func() {
+		log.Printf("draining for %s\n", cfg.ShutdownDrain)
+		h.Shutdown()
+	}))
+	hctx, hcancel := context.WithCancel(context.Background())
+	g.Add(func() error {
+		h.Run(hctx, cfg.HealthInterval, cfg.HealthTimeout)
+		return nil
+	}, func(error) {
+		hcancel()
+	})
+	g.Add(func() error {
+		log.Printf("starting grpc server on %s\n", lis.Addr().String())
+		return gs.Serve(lis)
+	}, func(error) {
+		lifecycle.GracefulStop(gs, cfg.ShutdownTimeout)
+	})
+	g.Add(func() error {
+		return gs.Serve(pipe)
+	}, func(error) {
+		// connections of the gateway are left open until the grpc server stops, so that proxied requests complete
+		_ = pipe.Close()
+	})
+	g.Add(func() error {
+		log.Printf("starting grpc proxy on %s\n", hl.Addr().String())
+		if err := hsrv.Serve(httpserver.NewListener(hl, cfg.HTTP)); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}, func(error) {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+
+		if err := hsrv.Shutdown(ctx); err != nil {
+			log.Printf("grpc proxy shutdown: %v\n", err)
+			_ = hsrv.Close()
+		}
+	})
+
+	return g.Run()
 }